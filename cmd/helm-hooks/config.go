@@ -0,0 +1,106 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io/fs"
+	"os"
+	"strconv"
+
+	"github.com/agk/helm-hooks/internal/hook"
+)
+
+const (
+	// configFileName is the configuration file looked up in the working directory.
+	configFileName = ".helm-hooks.yaml"
+	// configEnvVar names the environment variable holding the configuration file path.
+	configEnvVar = "HELM_HOOKS_CONFIG"
+)
+
+// addConfigFlag registers the --config flag on a subcommand's flag set.
+func addConfigFlag(flags *flag.FlagSet) *string {
+	return flags.String("config", "", "Configuration `file` (default $"+configEnvVar+" or ./"+configFileName+")")
+}
+
+// loadConfig returns the default configuration modified by the first
+// configuration file found: path (from --config), $HELM_HOOKS_CONFIG,
+// then .helm-hooks.yaml in the working directory. Only the last may be
+// missing.
+func loadConfig(path string) (hook.Config, error) {
+	cfg := hook.DefaultConfig()
+
+	optional := false
+	if path == "" {
+		path = os.Getenv(configEnvVar)
+	}
+	if path == "" {
+		path = configFileName
+		optional = true
+	}
+
+	file, err := hook.LoadConfigFile(path)
+	if err != nil {
+		if optional && errors.Is(err, fs.ErrNotExist) {
+			return cfg, nil
+		}
+		return cfg, fmt.Errorf("loading config: %w", err)
+	}
+	file.Apply(&cfg)
+	return cfg, nil
+}
+
+// flagPassed reports whether a flag was given on the command line, so it
+// overrides the configuration file only when set explicitly.
+func flagPassed(flags *flag.FlagSet, name string) bool {
+	passed := false
+	flags.Visit(func(f *flag.Flag) {
+		if f.Name == name {
+			passed = true
+		}
+	})
+	return passed
+}
+
+// releaseFlag is a release context flag and the environment variable
+// used when the flag is not given.
+type releaseFlag struct {
+	name, envVar, usage string
+	field               func(*hook.Release) *string
+}
+
+var releaseFlags = []releaseFlag{
+	{"release-name", "HELM_RELEASE_NAME", "Release name to inject as HELM_RELEASE_NAME", func(r *hook.Release) *string { return &r.Name }},
+	{"release-namespace", "HELM_RELEASE_NAMESPACE", "Release namespace to inject as HELM_RELEASE_NAMESPACE", func(r *hook.Release) *string { return &r.Namespace }},
+	{"release-revision", "HELM_RELEASE_REVISION", "Release revision to inject as HELM_RELEASE_REVISION", func(r *hook.Release) *string { return &r.Revision }},
+	{"chart-name", "HELM_CHART_NAME", "Chart name to inject as HELM_CHART_NAME", func(r *hook.Release) *string { return &r.Chart }},
+	{"chart-version", "HELM_CHART_VERSION", "Chart version to inject as HELM_CHART_VERSION", func(r *hook.Release) *string { return &r.ChartVersion }},
+}
+
+// addReleaseFlags registers the release context flags. The returned
+// function, called after parsing, reads each value from its flag or,
+// when not given, from the environment variable of the same name as the
+// injected one, e.g. as exported by a plugin wrapper script.
+func addReleaseFlags(flags *flag.FlagSet) func() (hook.Release, error) {
+	values := make([]*string, len(releaseFlags))
+	for i, f := range releaseFlags {
+		values[i] = flags.String(f.name, "", f.usage+" (default $"+f.envVar+")")
+	}
+
+	return func() (hook.Release, error) {
+		var release hook.Release
+		for i, f := range releaseFlags {
+			value := *values[i]
+			if !flagPassed(flags, f.name) {
+				value = os.Getenv(f.envVar)
+			}
+			*f.field(&release) = value
+		}
+		if release.Revision != "" {
+			if n, err := strconv.Atoi(release.Revision); err != nil || n < 1 {
+				return release, fmt.Errorf("invalid release revision %q", release.Revision)
+			}
+		}
+		return release, nil
+	}
+}
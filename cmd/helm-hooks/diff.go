@@ -0,0 +1,73 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/agk/helm-hooks/internal/hook"
+)
+
+// runDiff implements "helm-hooks diff [--format fmt] [files|-]".
+// It prints what the post-renderer would change in each document, as
+// per-field changes or as a unified diff.
+func runDiff(args []string) error {
+	fs := flag.NewFlagSet("diff", flag.ContinueOnError)
+	format := fs.String("format", "structural", "Output format: structural, unified")
+	profile := fs.String("profile", hook.ProfileHelm, "Output profile: "+strings.Join(hook.Profiles(), ", "))
+	flattenLists := fs.Bool("flatten-lists", false, "Emit the items of List documents as separate documents")
+	configFile := addConfigFlag(fs)
+	release := addReleaseFlags(fs)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: helm-hooks diff [--format fmt] [--profile profile] [--flatten-lists] [--config file] [files|-]")
+		fmt.Fprintln(fs.Output(), "\nShows what the post-renderer changes in each document, e.g.:")
+		fmt.Fprintln(fs.Output(), "  helm template ./chart | helm-hooks diff")
+		fmt.Fprintln(fs.Output(), "  helm template ./chart | helm-hooks diff --format unified")
+		fmt.Fprintln(fs.Output(), "\nFlags:")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	var write func(*hook.ManifestDiff) error
+	switch *format {
+	case "structural":
+		write = func(d *hook.ManifestDiff) error { return d.WriteText(os.Stdout) }
+	case "unified":
+		write = func(d *hook.ManifestDiff) error { return d.WriteUnified(os.Stdout) }
+	default:
+		return fmt.Errorf("unknown format %q (valid: structural, unified)", *format)
+	}
+
+	cfg, err := loadConfig(*configFile)
+	if err != nil {
+		return err
+	}
+	if cfg.Release, err = release(); err != nil {
+		return err
+	}
+	if flagPassed(fs, "profile") {
+		if !contains(hook.Profiles(), *profile) {
+			return fmt.Errorf("unknown profile %q (valid: %s)", *profile, strings.Join(hook.Profiles(), ", "))
+		}
+		cfg.Profile = *profile
+	}
+	if flagPassed(fs, "flatten-lists") {
+		cfg.FlattenLists = *flattenLists
+	}
+	cfg.OnWarning = printWarning
+
+	input, err := readInputs(fs.Args())
+	if err != nil {
+		return err
+	}
+
+	diff, err := hook.NewProcessor(cfg).Diff(input)
+	if err != nil {
+		return err
+	}
+
+	return write(diff)
+}
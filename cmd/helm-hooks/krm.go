@@ -0,0 +1,60 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/agk/helm-hooks/internal/hook"
+)
+
+// runKRM implements "helm-hooks krm": it reads a KRM function ResourceList
+// from stdin and writes the transformed ResourceList to stdout, so
+// helm-hooks can run in kustomize and kpt function pipelines.
+func runKRM(args []string) error {
+	fs := flag.NewFlagSet("krm", flag.ContinueOnError)
+	configFile := addConfigFlag(fs)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: helm-hooks krm [--config file] < resource-list.yaml")
+		fmt.Fprintln(fs.Output(), "\nRuns as a KRM function, e.g.:")
+		fmt.Fprintln(fs.Output(), "  kpt fn eval --exec helm-hooks ./manifests")
+		fmt.Fprintln(fs.Output(), "\nFlags:")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	cfg, err := loadConfig(*configFile)
+	if err != nil {
+		return err
+	}
+
+	input, err := io.ReadAll(os.Stdin)
+	if err != nil {
+		return fmt.Errorf("reading stdin: %w", err)
+	}
+
+	return processResourceList(hook.NewProcessor(cfg), input)
+}
+
+// processResourceList writes the function output, even when some items
+// were rejected, and returns an error in that case so the exit code
+// signals failure to the orchestrator.
+func processResourceList(processor *hook.Processor, input []byte) error {
+	output, err := processor.ProcessResourceList(input)
+	if output != nil {
+		if _, werr := os.Stdout.Write(output); werr != nil {
+			return fmt.Errorf("writing output: %w", werr)
+		}
+	}
+	if errors.Is(err, hook.ErrFunctionFailed) {
+		return err
+	}
+	if err != nil {
+		return fmt.Errorf("processing ResourceList: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/agk/helm-hooks/internal/hook"
+)
+
+// runLint implements "helm-hooks lint [files|-]".
+// It validates every document and reports all problems instead of
+// stopping at the first one. Reads stdin when no files are given.
+func runLint(args []string) error {
+	fs := flag.NewFlagSet("lint", flag.ContinueOnError)
+	strictOrdering := fs.Bool("strict-ordering", false, "Reject hooks of the same event that share a weight")
+	configFile := addConfigFlag(fs)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: helm-hooks lint [--strict-ordering] [--config file] [files|-]")
+		fmt.Fprintln(fs.Output(), "\nValidates hook annotations in rendered manifests, e.g.:")
+		fmt.Fprintln(fs.Output(), "  helm template ./chart | helm-hooks lint")
+		fmt.Fprintln(fs.Output(), "\nFlags:")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	cfg, err := loadConfig(*configFile)
+	if err != nil {
+		return err
+	}
+	if flagPassed(fs, "strict-ordering") {
+		cfg.StrictOrdering = *strictOrdering
+	}
+	processor := hook.NewProcessor(cfg)
+
+	files := fs.Args()
+	if len(files) == 0 {
+		files = []string{"-"}
+	}
+
+	total := 0
+	for _, file := range files {
+		input, name, err := readInput(file)
+		if err != nil {
+			return err
+		}
+
+		for _, p := range processor.Lint(input) {
+			fmt.Fprintf(os.Stdout, "%s: %s\n", name, p)
+			total++
+		}
+	}
+
+	if total > 0 {
+		return fmt.Errorf("lint found %d problem(s)", total)
+	}
+	return nil
+}
+
+// readInput reads a file, or stdin when file is "-".
+// It returns the content and a display name for messages.
+func readInput(file string) ([]byte, string, error) {
+	if file == "-" {
+		input, err := io.ReadAll(os.Stdin)
+		if err != nil {
+			return nil, "", fmt.Errorf("reading stdin: %w", err)
+		}
+		return input, "<stdin>", nil
+	}
+
+	input, err := os.ReadFile(file)
+	if err != nil {
+		return nil, "", fmt.Errorf("reading %s: %w", file, err)
+	}
+	return input, file, nil
+}
@@ -1,16 +1,31 @@
 // Package main provides the helm-hooks post-renderer binary.
 // It reads Helm-rendered YAML from stdin, enhances hook resources,
-// and outputs modified YAML to stdout.
+// and outputs modified YAML to stdout. The lint, plan and diff subcommands
+// validate manifests, show hook execution order and show what would
+// change without producing output, and the krm subcommand runs it as a
+// KRM function.
 package main
 
 import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"flag"
 	"fmt"
 	"io"
 	"os"
+	"os/signal"
+	"strings"
+	"syscall"
 
 	"github.com/agk/helm-hooks/internal/hook"
 )
 
+// resourceListPeekSize is how much of stdin is inspected to detect a KRM
+// ResourceList; its apiVersion and kind are expected near the top of the
+// first document.
+const resourceListPeekSize = 4096
+
 // Version information (set via ldflags during build)
 var (
 	Version   = "dev"
@@ -19,13 +34,28 @@ var (
 )
 
 func main() {
-	// Handle version command
-	if len(os.Args) > 1 && (os.Args[1] == "version" || os.Args[1] == "--version" || os.Args[1] == "-v") {
-		printVersion()
-		return
+	var err error
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "version", "--version", "-v":
+			printVersion()
+			return
+		case "lint":
+			err = runLint(os.Args[2:])
+		case "plan":
+			err = runPlan(os.Args[2:])
+		case "krm":
+			err = runKRM(os.Args[2:])
+		case "diff":
+			err = runDiff(os.Args[2:])
+		default:
+			err = run(os.Args[1:])
+		}
+	} else {
+		err = run(nil)
 	}
 
-	if err := run(); err != nil {
+	if err != nil {
 		fmt.Fprintf(os.Stderr, "helm-hooks: %v\n", err)
 		os.Exit(1)
 	}
@@ -37,24 +67,148 @@ func printVersion() {
 	fmt.Printf("  Build Date: %s\n", BuildDate)
 }
 
-func run() error {
-	// Read all YAML from stdin
-	input, err := io.ReadAll(os.Stdin)
-	if err != nil {
-		return fmt.Errorf("reading stdin: %w", err)
+// run is the post-renderer mode. Flags can be passed through Helm's
+// --post-renderer-args.
+func run(args []string) error {
+	fs := flag.NewFlagSet("helm-hooks", flag.ContinueOnError)
+	profile := fs.String("profile", hook.ProfileHelm, "Output profile: "+strings.Join(hook.Profiles(), ", "))
+	flattenLists := fs.Bool("flatten-lists", false, "Emit the items of List documents as separate documents")
+	strictOrdering := fs.Bool("strict-ordering", false, "Reject hooks of the same event that share a weight")
+	reportFile := fs.String("report", "", "Write a JSON report of what was done to each document to `file`")
+	eventEnvVar := fs.String("env-event-name", "", "Name of the injected hook event variable (default HELM_HOOK_EVENT)")
+	weightEnvVar := fs.String("env-weight-name", "", "Name of the injected hook weight variable (default HELM_HOOK_WEIGHT)")
+	envVarPrefix := fs.String("env-prefix", "", "Prefix of the injected hook event and weight variables, e.g. APP_")
+	nameTemplate := fs.String("name-template", "", "Go template naming split resources, e.g. '{{.Name}}-{{.EventShort}}'")
+	envMode := fs.String("env-mode", hook.EnvModeEnv, "How env vars reach containers: "+strings.Join(hook.EnvModes(), ", "))
+	envEphemeral := fs.Bool("env-ephemeral", false, "Also inject env vars into ephemeralContainers")
+	downwardEnv := fs.String("env-downward", "", "Downward API env sources: "+strings.Join(hook.DownwardSources(), ", "))
+	configFile := addConfigFlag(fs)
+	release := addReleaseFlags(fs)
+	if err := fs.Parse(args); err != nil {
+		return err
 	}
 
-	// Process the YAML through hook enhancement
-	output, err := hook.Process(input)
+	cfg, err := loadConfig(*configFile)
 	if err != nil {
-		return fmt.Errorf("processing hooks: %w", err)
+		return err
+	}
+	if cfg.Release, err = release(); err != nil {
+		return err
+	}
+	if flagPassed(fs, "profile") {
+		if !contains(hook.Profiles(), *profile) {
+			return fmt.Errorf("unknown profile %q (valid: %s)", *profile, strings.Join(hook.Profiles(), ", "))
+		}
+		cfg.Profile = *profile
+	}
+	if flagPassed(fs, "flatten-lists") {
+		cfg.FlattenLists = *flattenLists
+	}
+	if flagPassed(fs, "strict-ordering") {
+		cfg.StrictOrdering = *strictOrdering
+	}
+	if flagPassed(fs, "env-event-name") {
+		cfg.EventEnvVar = *eventEnvVar
 	}
+	if flagPassed(fs, "env-weight-name") {
+		cfg.WeightEnvVar = *weightEnvVar
+	}
+	if flagPassed(fs, "env-prefix") {
+		cfg.EnvVarPrefix = *envVarPrefix
+	}
+	if flagPassed(fs, "name-template") {
+		cfg.NameTemplate = *nameTemplate
+	}
+	if flagPassed(fs, "env-mode") {
+		if !contains(hook.EnvModes(), *envMode) {
+			return fmt.Errorf("unknown env mode %q (valid: %s)", *envMode, strings.Join(hook.EnvModes(), ", "))
+		}
+		cfg.EnvMode = *envMode
+	}
+	if flagPassed(fs, "env-ephemeral") {
+		cfg.EnvEphemeralContainers = *envEphemeral
+	}
+	if flagPassed(fs, "env-downward") {
+		cfg.DownwardEnv = nil
+		for _, source := range strings.Split(*downwardEnv, ",") {
+			if source = strings.TrimSpace(source); source == "" {
+				continue
+			}
+			if !contains(hook.DownwardSources(), source) {
+				return fmt.Errorf("unknown downward env source %q (valid: %s)", source, strings.Join(hook.DownwardSources(), ", "))
+			}
+			cfg.DownwardEnv = append(cfg.DownwardEnv, source)
+		}
+	}
+	cfg.OnWarning = printWarning
+	processor := hook.NewProcessor(cfg)
 
-	// Write result to stdout
-	_, err = os.Stdout.Write(output)
-	if err != nil {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	// A KRM ResourceList as the first document on stdin means we were
+	// invoked as a kustomize or kpt function, which cannot pass the krm
+	// subcommand. Rendered manifests are never misrouted: only the first
+	// document is inspected, and more documents after a ResourceList are
+	// rejected rather than dropped.
+	in := bufio.NewReaderSize(os.Stdin, resourceListPeekSize)
+	if head, _ := in.Peek(resourceListPeekSize); hook.IsResourceList(head) {
+		if *reportFile != "" {
+			return fmt.Errorf("--report is not supported for ResourceList input")
+		}
+		input, err := io.ReadAll(in)
+		if err != nil {
+			return fmt.Errorf("reading stdin: %w", err)
+		}
+		return processResourceList(processor, input)
+	}
+
+	// Stream YAML from stdin through hook enhancement to stdout
+	out := bufio.NewWriter(os.Stdout)
+	if *reportFile == "" {
+		if err := processor.ProcessStream(ctx, in, out); err != nil {
+			return fmt.Errorf("processing hooks: %w", err)
+		}
+	} else {
+		report, err := processor.ProcessStreamWithReport(ctx, in, out)
+		if err != nil {
+			return fmt.Errorf("processing hooks: %w", err)
+		}
+		if err := writeReport(*reportFile, report); err != nil {
+			return err
+		}
+	}
+
+	if err := out.Flush(); err != nil {
 		return fmt.Errorf("writing output: %w", err)
 	}
 
 	return nil
 }
+
+// writeReport writes a processing report to file as indented JSON.
+func writeReport(file string, report *hook.Report) error {
+	data, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encoding report: %w", err)
+	}
+	if err := os.WriteFile(file, append(data, '\n'), 0o644); err != nil {
+		return fmt.Errorf("writing report: %w", err)
+	}
+	return nil
+}
+
+// printWarning reports a processing warning on stderr.
+func printWarning(w hook.Warning) {
+	fmt.Fprintf(os.Stderr, "helm-hooks: warning: %s\n", w)
+}
+
+// contains reports whether list contains value.
+func contains(list []string, value string) bool {
+	for _, v := range list {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
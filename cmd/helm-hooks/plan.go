@@ -0,0 +1,86 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/agk/helm-hooks/internal/hook"
+)
+
+// runPlan implements "helm-hooks plan [--operation op] [--format fmt] [--flatten-lists] [files|-]".
+// It prints the order in which Helm runs hooks for the operation, as text
+// or as a Mermaid/Graphviz diagram.
+func runPlan(args []string) error {
+	fs := flag.NewFlagSet("plan", flag.ContinueOnError)
+	operation := fs.String("operation", "install", "Helm operation: "+strings.Join(hook.Operations(), ", "))
+	format := fs.String("format", "text", "Output format: text, mermaid, dot")
+	flattenLists := fs.Bool("flatten-lists", false, "Plan the items of List documents as separate documents")
+	configFile := addConfigFlag(fs)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: helm-hooks plan [--operation op] [--format fmt] [--flatten-lists] [--config file] [files|-]")
+		fmt.Fprintln(fs.Output(), "\nPrints the hook execution order for a Helm operation, e.g.:")
+		fmt.Fprintln(fs.Output(), "  helm template ./chart | helm-hooks plan --operation upgrade")
+		fmt.Fprintln(fs.Output(), "  helm template ./chart | helm-hooks plan --format mermaid")
+		fmt.Fprintln(fs.Output(), "\nFlags:")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	input, err := readInputs(fs.Args())
+	if err != nil {
+		return err
+	}
+
+	var write func(*hook.ExecutionPlan) error
+	switch *format {
+	case "text":
+		write = func(p *hook.ExecutionPlan) error { return p.WriteText(os.Stdout) }
+	case "mermaid":
+		write = func(p *hook.ExecutionPlan) error { return p.WriteMermaid(os.Stdout) }
+	case "dot":
+		write = func(p *hook.ExecutionPlan) error { return p.WriteDOT(os.Stdout) }
+	default:
+		return fmt.Errorf("unknown format %q (valid: text, mermaid, dot)", *format)
+	}
+
+	cfg, err := loadConfig(*configFile)
+	if err != nil {
+		return err
+	}
+	if flagPassed(fs, "flatten-lists") {
+		cfg.FlattenLists = *flattenLists
+	}
+
+	plan, err := hook.NewProcessor(cfg).Plan(input, *operation)
+	if err != nil {
+		return err
+	}
+
+	return write(plan)
+}
+
+// readInputs reads and concatenates the given files as one YAML stream.
+// Reads stdin when no files are given.
+func readInputs(files []string) ([]byte, error) {
+	if len(files) == 0 {
+		files = []string{"-"}
+	}
+
+	var buf bytes.Buffer
+	for i, file := range files {
+		input, _, err := readInput(file)
+		if err != nil {
+			return nil, err
+		}
+		if i > 0 {
+			buf.WriteString("\n---\n")
+		}
+		buf.Write(input)
+	}
+	return buf.Bytes(), nil
+}
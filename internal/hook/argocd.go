@@ -0,0 +1,128 @@
+package hook
+
+import (
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Argo CD resource hook annotations
+const (
+	annotationArgoHook         = "argocd.argoproj.io/hook"
+	annotationArgoSyncWave     = "argocd.argoproj.io/sync-wave"
+	annotationArgoDeletePolicy = "argocd.argoproj.io/hook-delete-policy"
+)
+
+// argoHooks maps Helm hook events to Argo CD hook types. Events in
+// validHooks without an entry have no Argo CD equivalent.
+var argoHooks = map[string]string{
+	"pre-install":  "PreSync",
+	"pre-upgrade":  "PreSync",
+	"post-install": "PostSync",
+	"post-upgrade": "PostSync",
+	"post-delete":  "PostDelete",
+}
+
+// argoDeletePolicies maps Helm hook delete policies to Argo CD ones.
+var argoDeletePolicies = map[string]string{
+	"before-hook-creation": "BeforeHookCreation",
+	"hook-succeeded":       "HookSucceeded",
+	"hook-failed":          "HookFailed",
+}
+
+// convertToArgoCD translates processed hook resources into Argo CD hooks.
+// processed is the output of transformDocument for node (nil if unchanged).
+//
+// Each output gets argocd.argoproj.io/hook and sync-wave from its hook
+// event and weight, and its Helm hook annotations are removed. An
+// argocd.argoproj.io/hook set in the chart is kept instead. Events
+// without an Argo CD equivalent are left as Helm hooks with a warning.
+// Argo CD has no install/upgrade distinction, so when several clones of
+// one resource map to the same Argo CD hook, only the first is kept,
+// along with its generated env ConfigMap.
+func (p *Processor) convertToArgoCD(node *yaml.Node, processed []*yaml.Node) ([]*yaml.Node, error) {
+	if processed == nil {
+		res, err := parseResource(node)
+		if err != nil {
+			return nil, err
+		}
+		if _, ok := res.Annotations[annotationHook]; !ok {
+			return nil, nil
+		}
+		processed = []*yaml.Node{node}
+	}
+
+	var results []*yaml.Node
+	seen := make(map[string]string)
+
+	for _, out := range processed {
+		res, err := parseResource(out)
+		if err != nil {
+			return nil, err
+		}
+
+		// An explicit Argo CD hook, e.g. SyncFail, which no Helm event
+		// maps to, takes precedence over the translation.
+		event := res.Annotations[annotationHook]
+		argoHook, ok := res.Annotations[annotationArgoHook], true
+		if strings.TrimSpace(argoHook) == "" {
+			argoHook, ok = argoHooks[event]
+		}
+		if !ok {
+			p.warn(res, "hook %q has no Argo CD equivalent; left as a Helm hook, which Argo CD does not run", event)
+			results = append(results, out)
+			continue
+		}
+
+		if first, dup := seen[res.Kind+"/"+argoHook]; dup {
+			p.warn(res, "hook %q maps to Argo CD %s like %q; dropped because Argo CD would run both on every sync", event, argoHook, first)
+			continue
+		}
+		seen[res.Kind+"/"+argoHook] = event
+
+		content := out
+		if out.Kind == yaml.DocumentNode && len(out.Content) > 0 {
+			content = out.Content[0]
+		}
+
+		weight, err := strconv.Atoi(strings.TrimSpace(res.Annotations[annotationHookWeight]))
+		if err != nil {
+			weight = defaultWeight
+		}
+
+		if err := setAnnotation(content, annotationArgoHook, argoHook); err != nil {
+			return nil, err
+		}
+		if err := setAnnotation(content, annotationArgoSyncWave, strconv.Itoa(weight)); err != nil {
+			return nil, err
+		}
+
+		if policy, ok := res.Annotations[annotationHookDeletePolicy]; ok {
+			var argoPolicies []string
+			for _, helmPolicy := range strings.Split(policy, ",") {
+				helmPolicy = strings.TrimSpace(helmPolicy)
+				if argoPolicy, ok := argoDeletePolicies[helmPolicy]; ok {
+					argoPolicies = append(argoPolicies, argoPolicy)
+				} else if helmPolicy != "" {
+					p.warn(res, "delete policy %q has no Argo CD equivalent", helmPolicy)
+				}
+			}
+			if len(argoPolicies) > 0 {
+				if err := setAnnotation(content, annotationArgoDeletePolicy, strings.Join(argoPolicies, ",")); err != nil {
+					return nil, err
+				}
+			}
+		}
+
+		// Argo CD also interprets Helm hook annotations; remove them so
+		// only the translated ones apply.
+		removeAnnotation(content, annotationHook)
+		removeAnnotation(content, annotationHookWeight)
+		removeAnnotation(content, annotationHookDeletePolicy)
+
+		results = append(results, out)
+	}
+
+	return results, nil
+}
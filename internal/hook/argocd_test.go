@@ -0,0 +1,161 @@
+package hook
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestProcess_ArgoCDProfile(t *testing.T) {
+	input := `apiVersion: batch/v1
+kind: Job
+metadata:
+  name: myapp-migration
+  annotations:
+    helm.sh/hook: pre-install,pre-upgrade,post-upgrade,pre-rollback
+    helm.sh/hook-weights: "-5,-3,10,1"
+    helm.sh/hook-delete-policy: before-hook-creation,hook-succeeded
+spec:
+  template:
+    spec:
+      containers:
+        - name: migrate
+          image: busybox
+`
+
+	cfg := DefaultConfig()
+	cfg.Profile = ProfileArgoCD
+	var warnings []Warning
+	cfg.OnWarning = func(w Warning) { warnings = append(warnings, w) }
+
+	output, err := NewProcessor(cfg).Process([]byte(input))
+	if err != nil {
+		t.Fatalf("Process failed: %v", err)
+	}
+
+	docs := strings.Split(string(output), "---\n")
+	if len(docs) != 3 {
+		t.Fatalf("Expected 3 documents (pre-upgrade collapsed), got %d:\n%s", len(docs), output)
+	}
+
+	pre := docs[0]
+	for _, want := range []string{
+		"name: myapp-migration-pre-install",
+		`argocd.argoproj.io/hook: "PreSync"`,
+		`argocd.argoproj.io/sync-wave: "-5"`,
+		`argocd.argoproj.io/hook-delete-policy: "BeforeHookCreation,HookSucceeded"`,
+	} {
+		if !strings.Contains(pre, want) {
+			t.Errorf("Expected %q in PreSync hook:\n%s", want, pre)
+		}
+	}
+	if strings.Contains(pre, "helm.sh/hook") {
+		t.Errorf("Helm hook annotations should be removed:\n%s", pre)
+	}
+
+	if !strings.Contains(docs[1], `argocd.argoproj.io/hook: "PostSync"`) || !strings.Contains(docs[1], `sync-wave: "10"`) {
+		t.Errorf("Unexpected PostSync hook:\n%s", docs[1])
+	}
+
+	// pre-rollback has no equivalent and stays a Helm hook
+	if !strings.Contains(docs[2], `helm.sh/hook: "pre-rollback"`) || strings.Contains(docs[2], "argocd.argoproj.io") {
+		t.Errorf("Expected pre-rollback left as Helm hook:\n%s", docs[2])
+	}
+
+	if len(warnings) != 2 {
+		t.Fatalf("Expected 2 warnings, got %v", warnings)
+	}
+	if !strings.Contains(warnings[0].Message, "pre-upgrade") || !strings.Contains(warnings[1].Message, "no Argo CD equivalent") {
+		t.Errorf("Unexpected warnings: %v", warnings)
+	}
+}
+
+func TestProcess_UnknownProfile(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Profile = "flux"
+
+	_, err := NewProcessor(cfg).Process([]byte("kind: ConfigMap\n"))
+	if err == nil || !strings.Contains(err.Error(), "unknown profile") {
+		t.Errorf("Expected unknown profile error, got: %v", err)
+	}
+}
+
+func TestProcess_ArgoCDProfileEnvFrom(t *testing.T) {
+	input := dependsJob("migrate", `    helm.sh/hook: pre-install,pre-upgrade
+    helm.sh/hook-env-mode: envFrom
+`)
+
+	cfg := DefaultConfig()
+	cfg.Profile = ProfileArgoCD
+	output, err := NewProcessor(cfg).Process([]byte(input))
+	if err != nil {
+		t.Fatalf("Process failed: %v", err)
+	}
+
+	// The pre-upgrade Job and its ConfigMap are both dropped
+	docs := strings.Split(string(output), "---\n")
+	if len(docs) != 2 || !strings.Contains(docs[0], "kind: ConfigMap") || !strings.Contains(docs[1], "kind: Job") {
+		t.Fatalf("Expected the pre-install ConfigMap and Job, got:\n%s", output)
+	}
+	if !strings.Contains(docs[0], `argocd.argoproj.io/sync-wave: "-1"`) {
+		t.Errorf("Expected the ConfigMap in an earlier sync wave, got:\n%s", docs[0])
+	}
+}
+
+func TestProcess_ArgoCDProfileTestFailure(t *testing.T) {
+	// Helm 3 never runs test-failure hooks, and Argo CD SyncFail would
+	// run on every failed sync, so it is not translated.
+	input := dependsJob("smoke", "    helm.sh/hook: test-failure\n")
+
+	cfg := DefaultConfig()
+	cfg.Profile = ProfileArgoCD
+	var warnings []Warning
+	cfg.OnWarning = func(w Warning) { warnings = append(warnings, w) }
+
+	output, err := NewProcessor(cfg).Process([]byte(input))
+	if err != nil {
+		t.Fatalf("Process failed: %v", err)
+	}
+	if strings.Contains(string(output), "argocd.argoproj.io") || !strings.Contains(string(output), "helm.sh/hook: test-failure") {
+		t.Errorf("Expected test-failure left as a Helm hook:\n%s", output)
+	}
+	if len(warnings) != 1 || !strings.Contains(warnings[0].Message, `hook "test-failure" has no Argo CD equivalent`) {
+		t.Errorf("Unexpected warnings: %v", warnings)
+	}
+}
+
+func TestProcess_ArgoCDProfileExplicitHook(t *testing.T) {
+	input := dependsJob("notify", `    helm.sh/hook: post-install,post-upgrade
+    helm.sh/hook-weight: "3"
+    argocd.argoproj.io/hook: SyncFail
+`)
+
+	cfg := DefaultConfig()
+	cfg.Profile = ProfileArgoCD
+	var warnings []Warning
+	cfg.OnWarning = func(w Warning) { warnings = append(warnings, w) }
+
+	output, err := NewProcessor(cfg).Process([]byte(input))
+	if err != nil {
+		t.Fatalf("Process failed: %v", err)
+	}
+
+	docs := strings.Split(string(output), "---\n")
+	if len(docs) != 1 {
+		t.Fatalf("Expected post-upgrade collapsed into one SyncFail hook, got:\n%s", output)
+	}
+	for _, want := range []string{
+		"name: notify-post-install",
+		`argocd.argoproj.io/hook: "SyncFail"`,
+		`argocd.argoproj.io/sync-wave: "3"`,
+	} {
+		if !strings.Contains(docs[0], want) {
+			t.Errorf("Expected %q in SyncFail hook:\n%s", want, docs[0])
+		}
+	}
+	if strings.Contains(docs[0], "helm.sh/hook") {
+		t.Errorf("Helm hook annotations should be removed:\n%s", docs[0])
+	}
+	if len(warnings) != 1 || !strings.Contains(warnings[0].Message, "maps to Argo CD SyncFail") {
+		t.Errorf("Unexpected warnings: %v", warnings)
+	}
+}
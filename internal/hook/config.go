@@ -0,0 +1,293 @@
+package hook
+
+import (
+	"fmt"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// defaultAnnotationPrefix is the prefix of all hook annotations.
+const defaultAnnotationPrefix = "helm.sh/"
+
+// extensionAnnotations are the annotations defined by helm-hooks rather
+// than Helm. Only these follow Config.AnnotationPrefix; native Helm
+// annotations are always read and written under helm.sh/.
+var extensionAnnotations = map[string]bool{
+	annotationHookWeights:              true,
+	annotationHookEnv:                  true,
+	annotationHookNameSuffix:           true,
+	annotationHookNameTemplate:         true,
+	annotationHookDeletePolicies:       true,
+	annotationHookEnvVars:              true,
+	annotationHookEnvNames:             true,
+	annotationHookEnvPrefix:            true,
+	annotationHookEnvDownward:          true,
+	annotationHookEnvPaths:             true,
+	annotationHookEnvMode:              true,
+	annotationHookEnvContainers:        true,
+	annotationHookEnvExcludeContainers: true,
+	annotationHookPatch:                true,
+	annotationHookDependsOn:            true,
+}
+
+// Output profiles select which tool the processed hooks are written for.
+const (
+	// ProfileHelm keeps Helm hook annotations (default).
+	ProfileHelm = "helm"
+	// ProfileArgoCD translates hooks into Argo CD hooks and sync waves.
+	ProfileArgoCD = "argocd"
+)
+
+// Profiles returns the supported output profiles.
+func Profiles() []string {
+	return []string{ProfileHelm, ProfileArgoCD}
+}
+
+// validProfile reports whether profile is a supported output profile.
+func validProfile(profile string) bool {
+	for _, p := range Profiles() {
+		if p == profile {
+			return true
+		}
+	}
+	return false
+}
+
+// DownwardSources returns the supported Downward API env sources.
+func DownwardSources() []string {
+	return []string{downwardPod, downwardHook}
+}
+
+// EnvModes returns the supported env modes.
+func EnvModes() []string {
+	return []string{EnvModeEnv, EnvModeEnvFrom}
+}
+
+// validEnvMode reports whether mode is a supported env mode.
+func validEnvMode(mode string) bool {
+	return mode == EnvModeEnv || mode == EnvModeEnvFrom
+}
+
+// Warning is a non-fatal problem found while processing a resource.
+type Warning struct {
+	Kind    string
+	Name    string
+	Message string
+}
+
+// String formats the warning as "Kind/name: message".
+func (w Warning) String() string {
+	return fmt.Sprintf("%s/%s: %s", w.Kind, w.Name, w.Message)
+}
+
+// Release describes the Helm release being rendered. Helm passes no
+// release metadata to post-renderers, so callers supply it, e.g. from
+// flags. Each non-empty field is injected as an env var.
+type Release struct {
+	// Name is injected as HELM_RELEASE_NAME.
+	Name string
+	// Namespace is injected as HELM_RELEASE_NAMESPACE.
+	Namespace string
+	// Revision is injected as HELM_RELEASE_REVISION.
+	Revision string
+	// Chart is injected as HELM_CHART_NAME.
+	Chart string
+	// ChartVersion is injected as HELM_CHART_VERSION.
+	ChartVersion string
+}
+
+// Config controls how a Processor handles hook resources.
+type Config struct {
+	// EnvDefault enables env injection when helm.sh/hook-env is absent.
+	EnvDefault bool
+	// NameSuffixDefault enables name suffixes when helm.sh/hook-name-suffix is absent.
+	NameSuffixDefault bool
+	// AllowedHooks restricts the accepted hook events.
+	// Nil allows every valid Helm hook event.
+	AllowedHooks map[string]bool
+	// MaxNameLength is the name length limit for split resources. ConfigMaps
+	// and Secrets allow 253 characters regardless, and CronJobs at most 52.
+	MaxNameLength int
+	// AnnotationPrefix replaces "helm.sh/" in helm-hooks' own annotations,
+	// e.g. "example.com/" reads example.com/hook-weights.
+	AnnotationPrefix string
+	// FlattenLists emits the items of List documents as separate
+	// documents instead of processing them within the list.
+	FlattenLists bool
+	// Profile selects the output format of hook annotations.
+	// Empty is the same as ProfileHelm.
+	Profile string
+	// StrictOrdering rejects hooks of the same event that share a weight,
+	// whose relative order Helm decides by name.
+	StrictOrdering bool
+	// EventEnvVar and WeightEnvVar name the injected hook event and weight
+	// variables. Empty uses HELM_HOOK_EVENT and HELM_HOOK_WEIGHT.
+	EventEnvVar  string
+	WeightEnvVar string
+	// EnvVarPrefix is prepended to the names of the injected hook event
+	// and weight variables, e.g. "APP_".
+	EnvVarPrefix string
+	// Release, if set, is injected into hook containers next to the
+	// hook event and weight.
+	Release Release
+	// DownwardEnv selects Downward API variables by source: "pod" injects
+	// POD_NAME, POD_NAMESPACE and NODE_NAME, "hook" reads the hook event
+	// and weight from pod annotations instead of literal values.
+	DownwardEnv []string
+	// ContainerPaths locates the containers of custom resources for env
+	// injection. Entries take precedence over the built-in ones.
+	ContainerPaths []ContainerPaths
+	// NameTemplate, if set, names the clones of split resources when
+	// helm.sh/hook-name-template is absent, e.g. "{{.Name}}-{{.EventShort}}".
+	NameTemplate string
+	// EnvEphemeralContainers also injects into ephemeralContainers.
+	EnvEphemeralContainers bool
+	// EnvMode selects how variables reach containers: EnvModeEnv (or
+	// empty) edits each env list, EnvModeEnvFrom generates a ConfigMap
+	// per hook.
+	EnvMode string
+	// IncludeKinds, if set, restricts processing to hooks of these kinds.
+	// Hooks of other kinds are left unchanged.
+	IncludeKinds map[string]bool
+	// ExcludeKinds leaves hooks of these kinds unchanged.
+	ExcludeKinds map[string]bool
+	// OnWarning, if set, receives non-fatal problems.
+	OnWarning func(Warning)
+}
+
+// DefaultConfig returns the configuration used by Process.
+func DefaultConfig() Config {
+	return Config{
+		EnvDefault:        true,
+		NameSuffixDefault: true,
+		MaxNameLength:     maxNameLength,
+		AnnotationPrefix:  defaultAnnotationPrefix,
+	}
+}
+
+// annotationKey returns the configured key for a helm-hooks annotation.
+func (c Config) annotationKey(key string) string {
+	if !extensionAnnotations[key] || c.AnnotationPrefix == "" || c.AnnotationPrefix == defaultAnnotationPrefix {
+		return key
+	}
+	return c.AnnotationPrefix + strings.TrimPrefix(key, defaultAnnotationPrefix)
+}
+
+// hookAllowed reports whether a hook event is valid and permitted.
+func (c Config) hookAllowed(hookEvent string) bool {
+	if c.AllowedHooks == nil {
+		return true
+	}
+	return c.AllowedHooks[hookEvent]
+}
+
+// nameLength returns the effective name length limit.
+func (c Config) nameLength() int {
+	if c.MaxNameLength <= 0 {
+		return maxNameLength
+	}
+	return c.MaxNameLength
+}
+
+// checkMaxNameLength rejects name length limits too short to fit a
+// truncated name.
+func checkMaxNameLength(n int) error {
+	if n < minNameLength {
+		return fmt.Errorf("invalid maxNameLength %d, must be at least %d", n, minNameLength)
+	}
+	return nil
+}
+
+// nameRule returns the name rule of a kind. MaxNameLength replaces the
+// default limit and tightens the limits of kinds that are shorter than
+// the 253 characters allowed for most resources, e.g. CronJob.
+func (c Config) nameRule(kind string) (nameRule, error) {
+	if err := checkMaxNameLength(c.nameLength()); err != nil {
+		return nameRule{}, err
+	}
+	rule, ok := kindNameRules[kind]
+	if !ok {
+		return nameRule{maxLen: c.nameLength()}, nil
+	}
+	if rule.maxLen <= maxNameLength && c.nameLength() < rule.maxLen {
+		rule.maxLen = c.nameLength()
+	}
+	return rule, nil
+}
+
+// eventEnvVar returns the name of the injected hook event variable.
+func (c Config) eventEnvVar() string {
+	if c.EventEnvVar == "" {
+		return envHookEvent
+	}
+	return c.EventEnvVar
+}
+
+// weightEnvVar returns the name of the injected hook weight variable.
+func (c Config) weightEnvVar() string {
+	if c.WeightEnvVar == "" {
+		return envHookWeight
+	}
+	return c.WeightEnvVar
+}
+
+// kindSelected reports whether hooks of a kind are processed.
+func (c Config) kindSelected(kind string) bool {
+	if c.ExcludeKinds[kind] {
+		return false
+	}
+	return c.IncludeKinds == nil || c.IncludeKinds[kind]
+}
+
+// Processor enhances hook resources according to its Config.
+type Processor struct {
+	cfg Config
+	// recorder, if set, collects a Report during processing.
+	recorder *reportRecorder
+	// observe, if set, is called with each input document and its
+	// outputs (nil if unchanged) before they are written.
+	observe func(index int, raw []byte, processed []*yaml.Node)
+	// generated, if set, collects the resources helm-hooks creates during
+	// ProcessStream, such as env ConfigMaps.
+	generated generatedSet
+}
+
+// forStream returns a copy of p that tracks the resources it generates,
+// so a release validator can tell them apart from the input's.
+func (p *Processor) forStream() *Processor {
+	stream := *p
+	stream.generated = make(generatedSet)
+	return &stream
+}
+
+// generatedSet holds the resource mappings created by helm-hooks. All
+// methods are safe on a nil set.
+type generatedSet map[*yaml.Node]bool
+
+// add records a generated resource.
+func (s generatedSet) add(node *yaml.Node) {
+	if s == nil {
+		return
+	}
+	if node.Kind == yaml.DocumentNode && len(node.Content) > 0 {
+		node = node.Content[0]
+	}
+	s[node] = true
+}
+
+// NewProcessor returns a Processor using cfg.
+func NewProcessor(cfg Config) *Processor {
+	return &Processor{cfg: cfg}
+}
+
+// defaultProcessor backs the package-level functions.
+var defaultProcessor = NewProcessor(DefaultConfig())
+
+// warn reports a non-fatal problem for a resource.
+func (p *Processor) warn(res *Resource, format string, args ...interface{}) {
+	if p.cfg.OnWarning == nil {
+		return
+	}
+	p.cfg.OnWarning(Warning{Kind: res.Kind, Name: res.Name, Message: fmt.Sprintf(format, args...)})
+}
@@ -0,0 +1,222 @@
+package hook
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/template"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ConfigFile is the content of a .helm-hooks.yaml configuration file.
+// It sets organisation-wide defaults; per-resource annotations still
+// take precedence. Unset fields keep the current configuration.
+//
+//	env: false
+//	nameSuffix: true
+//	nameTemplate: "{{.Name}}-{{.EventShort}}"
+//	envVars:
+//	  event: HOOK_EVENT
+//	  weight: HOOK_WEIGHT
+//	  prefix: APP_
+//	  downward: [pod]
+//	  mode: envFrom
+//	maxNameLength: 52
+//	allowedHooks: [pre-install, pre-upgrade, post-install, post-upgrade]
+//	kinds:
+//	  include: [Job, Pod]
+//	containerPaths:
+//	  - apiVersion: example.com
+//	    kind: BatchRun
+//	    paths: ["spec.runner.containers[]"]
+type ConfigFile struct {
+	// Env is the default of helm.sh/hook-env.
+	Env *bool `yaml:"env"`
+	// NameSuffix is the default of helm.sh/hook-name-suffix.
+	NameSuffix *bool `yaml:"nameSuffix"`
+	// NameTemplate is the default of helm.sh/hook-name-template.
+	NameTemplate string `yaml:"nameTemplate"`
+	// EnvVars names the injected variables.
+	EnvVars ConfigFileEnvVars `yaml:"envVars"`
+	// MaxNameLength is the name length limit for split resources.
+	MaxNameLength int `yaml:"maxNameLength"`
+	// AllowedHooks restricts the accepted hook events.
+	AllowedHooks []string `yaml:"allowedHooks"`
+	// Kinds selects the kinds of hook resources that are processed.
+	Kinds ConfigFileKinds `yaml:"kinds"`
+	// AnnotationPrefix replaces "helm.sh/" in helm-hooks' own annotations.
+	AnnotationPrefix string `yaml:"annotationPrefix"`
+	// FlattenLists emits the items of List documents as separate documents.
+	FlattenLists *bool `yaml:"flattenLists"`
+	// Profile selects the output format of hook annotations.
+	Profile string `yaml:"profile"`
+	// StrictOrdering rejects hooks of the same event that share a weight.
+	StrictOrdering *bool `yaml:"strictOrdering"`
+	// ContainerPaths locates the containers of custom resources.
+	ContainerPaths []ContainerPaths `yaml:"containerPaths"`
+}
+
+// ConfigFileEnvVars names the variables injected into hook containers.
+type ConfigFileEnvVars struct {
+	Event  string `yaml:"event"`
+	Weight string `yaml:"weight"`
+	// Prefix is prepended to both names, e.g. "APP_".
+	Prefix string `yaml:"prefix"`
+	// Downward selects Downward API sources: pod, hook.
+	Downward []string `yaml:"downward"`
+	// Mode selects how variables reach containers: env, envFrom.
+	Mode string `yaml:"mode"`
+	// EphemeralContainers also injects into ephemeralContainers.
+	EphemeralContainers *bool `yaml:"ephemeralContainers"`
+}
+
+// ConfigFileKinds selects resources by kind. Hooks of other kinds are
+// left unchanged.
+type ConfigFileKinds struct {
+	// Include, if set, processes only these kinds.
+	Include []string `yaml:"include"`
+	// Exclude never processes these kinds.
+	Exclude []string `yaml:"exclude"`
+}
+
+// LoadConfigFile reads and validates a configuration file.
+func LoadConfigFile(path string) (*ConfigFile, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	f, err := ParseConfigFile(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return f, nil
+}
+
+// ParseConfigFile parses and validates the content of a configuration
+// file. Unknown keys are rejected.
+func ParseConfigFile(data []byte) (*ConfigFile, error) {
+	f := &ConfigFile{}
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	if err := dec.Decode(f); err != nil && !errors.Is(err, io.EOF) {
+		return nil, err
+	}
+
+	for _, name := range []string{f.EnvVars.Event, f.EnvVars.Weight, f.EnvVars.Prefix} {
+		if name != "" && !envVarNamePattern.MatchString(name) {
+			return nil, fmt.Errorf("envVars: invalid env var name %q", name)
+		}
+	}
+	if f.EnvVars.Event != "" && f.EnvVars.Event == f.EnvVars.Weight {
+		return nil, fmt.Errorf("envVars: event and weight use the same name %q", f.EnvVars.Event)
+	}
+	var names envNames
+	if err := names.setDownward(f.EnvVars.Downward); err != nil {
+		return nil, fmt.Errorf("envVars: %w", err)
+	}
+	if f.EnvVars.Mode != "" && !validEnvMode(f.EnvVars.Mode) {
+		return nil, fmt.Errorf("envVars: unknown env mode %q", f.EnvVars.Mode)
+	}
+	if f.NameTemplate != "" {
+		if _, err := template.New("name").Parse(f.NameTemplate); err != nil {
+			return nil, fmt.Errorf("invalid nameTemplate: %w", err)
+		}
+	}
+	if f.MaxNameLength != 0 {
+		if err := checkMaxNameLength(f.MaxNameLength); err != nil {
+			return nil, err
+		}
+	}
+	for _, h := range f.AllowedHooks {
+		if !validHooks[h] {
+			return nil, fmt.Errorf("allowedHooks: invalid hook %q", h)
+		}
+	}
+	if f.Profile != "" && !validProfile(f.Profile) {
+		return nil, fmt.Errorf("unknown profile %q", f.Profile)
+	}
+	for i, entry := range f.ContainerPaths {
+		if entry.Kind == "" || len(entry.Paths) == 0 {
+			return nil, fmt.Errorf("containerPaths[%d]: kind and paths are required", i)
+		}
+		if err := validateContainerPaths(entry.Paths); err != nil {
+			return nil, fmt.Errorf("containerPaths[%d]: %w", i, err)
+		}
+	}
+	return f, nil
+}
+
+// Apply overrides cfg with the fields set in the file.
+func (f *ConfigFile) Apply(cfg *Config) {
+	if f.Env != nil {
+		cfg.EnvDefault = *f.Env
+	}
+	if f.NameSuffix != nil {
+		cfg.NameSuffixDefault = *f.NameSuffix
+	}
+	if f.NameTemplate != "" {
+		cfg.NameTemplate = f.NameTemplate
+	}
+	if f.EnvVars.Event != "" {
+		cfg.EventEnvVar = f.EnvVars.Event
+	}
+	if f.EnvVars.Weight != "" {
+		cfg.WeightEnvVar = f.EnvVars.Weight
+	}
+	if f.EnvVars.Prefix != "" {
+		cfg.EnvVarPrefix = f.EnvVars.Prefix
+	}
+	if f.EnvVars.Downward != nil {
+		cfg.DownwardEnv = f.EnvVars.Downward
+	}
+	if f.EnvVars.Mode != "" {
+		cfg.EnvMode = f.EnvVars.Mode
+	}
+	if f.EnvVars.EphemeralContainers != nil {
+		cfg.EnvEphemeralContainers = *f.EnvVars.EphemeralContainers
+	}
+	if f.MaxNameLength > 0 {
+		cfg.MaxNameLength = f.MaxNameLength
+	}
+	if f.AllowedHooks != nil {
+		cfg.AllowedHooks = stringSet(f.AllowedHooks)
+	}
+	if f.Kinds.Include != nil {
+		cfg.IncludeKinds = stringSet(f.Kinds.Include)
+	}
+	if f.Kinds.Exclude != nil {
+		cfg.ExcludeKinds = stringSet(f.Kinds.Exclude)
+	}
+	if f.AnnotationPrefix != "" {
+		prefix := f.AnnotationPrefix
+		if !strings.HasSuffix(prefix, "/") {
+			prefix += "/"
+		}
+		cfg.AnnotationPrefix = prefix
+	}
+	if f.FlattenLists != nil {
+		cfg.FlattenLists = *f.FlattenLists
+	}
+	if f.Profile != "" {
+		cfg.Profile = f.Profile
+	}
+	if f.StrictOrdering != nil {
+		cfg.StrictOrdering = *f.StrictOrdering
+	}
+	if f.ContainerPaths != nil {
+		cfg.ContainerPaths = f.ContainerPaths
+	}
+}
+
+// stringSet returns a set of the given values.
+func stringSet(values []string) map[string]bool {
+	set := make(map[string]bool, len(values))
+	for _, v := range values {
+		set[v] = true
+	}
+	return set
+}
@@ -0,0 +1,140 @@
+package hook
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseConfigFile(t *testing.T) {
+	f, err := ParseConfigFile([]byte(`env: false
+nameSuffix: false
+envVars:
+  event: HOOK_EVENT
+maxNameLength: 52
+allowedHooks: [pre-install, post-install]
+kinds:
+  include: [Job]
+  exclude: [Pod]
+profile: argocd
+`))
+	if err != nil {
+		t.Fatalf("ParseConfigFile failed: %v", err)
+	}
+
+	cfg := DefaultConfig()
+	f.Apply(&cfg)
+
+	if cfg.EnvDefault || cfg.NameSuffixDefault {
+		t.Errorf("Expected env and name suffix disabled, got %+v", cfg)
+	}
+	if cfg.eventEnvVar() != "HOOK_EVENT" || cfg.weightEnvVar() != envHookWeight {
+		t.Errorf("Unexpected env var names %q, %q", cfg.eventEnvVar(), cfg.weightEnvVar())
+	}
+	if cfg.MaxNameLength != 52 || cfg.Profile != ProfileArgoCD {
+		t.Errorf("Unexpected config %+v", cfg)
+	}
+	if !cfg.hookAllowed("pre-install") || cfg.hookAllowed("pre-upgrade") {
+		t.Errorf("Unexpected allowed hooks %v", cfg.AllowedHooks)
+	}
+	if !cfg.kindSelected("Job") || cfg.kindSelected("Pod") || cfg.kindSelected("ConfigMap") {
+		t.Errorf("Unexpected kind filter %v / %v", cfg.IncludeKinds, cfg.ExcludeKinds)
+	}
+}
+
+func TestParseConfigFile_Empty(t *testing.T) {
+	f, err := ParseConfigFile(nil)
+	if err != nil {
+		t.Fatalf("ParseConfigFile failed: %v", err)
+	}
+
+	cfg := DefaultConfig()
+	f.Apply(&cfg)
+	if !cfg.EnvDefault || !cfg.NameSuffixDefault || cfg.MaxNameLength != maxNameLength {
+		t.Errorf("Empty file should keep the defaults, got %+v", cfg)
+	}
+}
+
+func TestParseConfigFile_Errors(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{name: "unknown key", input: "envDefault: true\n", want: "field envDefault not found"},
+		{name: "invalid env var name", input: "envVars:\n  event: 1EVENT\n", want: `invalid env var name "1EVENT"`},
+		{name: "same env var names", input: "envVars:\n  event: X\n  weight: X\n", want: "same name"},
+		{name: "unknown downward source", input: "envVars:\n  downward: [node]\n", want: `unknown downward env source "node"`},
+		{name: "container paths without kind", input: "containerPaths:\n  - paths: [\"spec.steps[]\"]\n", want: "containerPaths[0]: kind and paths are required"},
+		{name: "invalid container path", input: "containerPaths:\n  - kind: Task\n    paths: [\"spec.steps[0]\"]\n", want: `containerPaths[0]: invalid container path "spec.steps[0]"`},
+		{name: "unknown env mode", input: "envVars:\n  mode: volume\n", want: `unknown env mode "volume"`},
+		{name: "invalid name template", input: "nameTemplate: \"{{.Name\"\n", want: "invalid nameTemplate"},
+		{name: "invalid hook", input: "allowedHooks: [pre-instal]\n", want: `invalid hook "pre-instal"`},
+		{name: "unknown profile", input: "profile: flux\n", want: `unknown profile "flux"`},
+		{name: "negative length", input: "maxNameLength: -1\n", want: "invalid maxNameLength"},
+		{name: "length too short", input: "maxNameLength: 20\n", want: "invalid maxNameLength 20, must be at least 24"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseConfigFile([]byte(tt.input))
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Expected error containing %q, got: %v", tt.want, err)
+			}
+		})
+	}
+}
+
+func TestProcess_KindFilter(t *testing.T) {
+	input := dependsJob("migrate", `    helm.sh/hook: pre-install,post-install
+`) + `---
+apiVersion: v1
+kind: Pod
+metadata:
+  name: seed
+  annotations:
+    helm.sh/hook: pre-install,post-install
+spec:
+  containers:
+    - name: main
+      image: busybox
+`
+
+	cfg := DefaultConfig()
+	cfg.ExcludeKinds = map[string]bool{"Pod": true}
+	output, err := NewProcessor(cfg).Process([]byte(input))
+	if err != nil {
+		t.Fatalf("Process failed: %v", err)
+	}
+
+	result := string(output)
+	if !strings.Contains(result, "name: migrate-pre-install") {
+		t.Errorf("Expected the Job to be split, got:\n%s", result)
+	}
+	if !strings.Contains(result, "name: seed\n  annotations:\n    helm.sh/hook: pre-install,post-install\n") {
+		t.Errorf("Expected the excluded Pod to be unchanged, got:\n%s", result)
+	}
+}
+
+func TestProcess_CustomEnvVarNames(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.EventEnvVar = "HOOK_EVENT"
+	cfg.WeightEnvVar = "HOOK_WEIGHT"
+	input := dependsJob("migrate", `    helm.sh/hook: pre-install
+    helm.sh/hook-env-pre-install: "HOOK_EVENT: x"
+`)
+
+	// The renamed built-ins are reserved instead of the defaults
+	_, err := NewProcessor(cfg).Process([]byte(input))
+	if err == nil || !strings.Contains(err.Error(), `env var "HOOK_EVENT" is set by helm-hooks`) {
+		t.Errorf("Expected reserved name error, got: %v", err)
+	}
+
+	output, err := NewProcessor(cfg).Process([]byte(dependsJob("migrate", `    helm.sh/hook: pre-install
+`)))
+	if err != nil {
+		t.Fatalf("Process failed: %v", err)
+	}
+	if !strings.Contains(string(output), "name: HOOK_EVENT") || !strings.Contains(string(output), "name: HOOK_WEIGHT") {
+		t.Errorf("Expected renamed env vars, got:\n%s", output)
+	}
+}
@@ -0,0 +1,141 @@
+package hook
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ContainerPaths tells env injection where the containers of a custom
+// resource are, for kinds whose pods are not at spec.template,
+// spec.jobTemplate or spec.containers.
+//
+// Paths are dot-separated keys; a "[]" suffix visits every item of a
+// list. A path ends at a list of containers ("spec.steps[]") or a single
+// container ("spec.templates[].container"). Only existing containers
+// are injected into.
+type ContainerPaths struct {
+	// APIVersion, if set, restricts the entry to a group ("tekton.dev")
+	// or group/version ("tekton.dev/v1").
+	APIVersion string   `yaml:"apiVersion" json:"apiVersion,omitempty"`
+	Kind       string   `yaml:"kind" json:"kind"`
+	Paths      []string `yaml:"paths" json:"paths"`
+}
+
+// builtinContainerPaths covers common CRDs that run hook workloads.
+var builtinContainerPaths = []ContainerPaths{
+	// Argo Workflows
+	{APIVersion: "argoproj.io", Kind: "Workflow", Paths: argoTemplatePaths("spec.templates[]")},
+	{APIVersion: "argoproj.io", Kind: "WorkflowTemplate", Paths: argoTemplatePaths("spec.templates[]")},
+	{APIVersion: "argoproj.io", Kind: "ClusterWorkflowTemplate", Paths: argoTemplatePaths("spec.templates[]")},
+	{APIVersion: "argoproj.io", Kind: "CronWorkflow", Paths: argoTemplatePaths("spec.workflowSpec.templates[]")},
+	// Tekton Pipelines
+	{APIVersion: "tekton.dev", Kind: "Task", Paths: []string{"spec.steps[]", "spec.sidecars[]"}},
+	{APIVersion: "tekton.dev", Kind: "TaskRun", Paths: []string{"spec.taskSpec.steps[]", "spec.taskSpec.sidecars[]"}},
+	{APIVersion: "tekton.dev", Kind: "PipelineRun", Paths: []string{
+		"spec.pipelineSpec.tasks[].taskSpec.steps[]",
+		"spec.pipelineSpec.tasks[].taskSpec.sidecars[]",
+		"spec.pipelineSpec.finally[].taskSpec.steps[]",
+		"spec.pipelineSpec.finally[].taskSpec.sidecars[]",
+	}},
+	// KEDA
+	{APIVersion: "keda.sh", Kind: "ScaledJob", Paths: []string{
+		"spec.jobTargetRef.template.spec.containers[]",
+		"spec.jobTargetRef.template.spec.initContainers[]",
+	}},
+	// Strimzi container templates
+	{APIVersion: "kafka.strimzi.io", Kind: "KafkaConnect", Paths: []string{"spec.template.connectContainer"}},
+	{APIVersion: "kafka.strimzi.io", Kind: "KafkaMirrorMaker2", Paths: []string{"spec.template.connectContainer"}},
+	{APIVersion: "kafka.strimzi.io", Kind: "KafkaBridge", Paths: []string{"spec.template.bridgeContainer"}},
+}
+
+// argoTemplatePaths returns the container paths of Argo Workflows
+// templates at the given list path.
+func argoTemplatePaths(templates string) []string {
+	return []string{
+		templates + ".container",
+		templates + ".script",
+		templates + ".containerSet.containers[]",
+		templates + ".initContainers[]",
+		templates + ".sidecars[]",
+	}
+}
+
+// containerPathPattern matches a container path such as spec.templates[].container.
+var containerPathPattern = regexp.MustCompile(`^[A-Za-z0-9_-]+(\[\])?(\.[A-Za-z0-9_-]+(\[\])?)*$`)
+
+// validateContainerPaths checks the syntax of each path.
+func validateContainerPaths(paths []string) error {
+	for _, path := range paths {
+		if !containerPathPattern.MatchString(path) {
+			return fmt.Errorf("invalid container path %q", path)
+		}
+	}
+	return nil
+}
+
+// matches reports whether the entry applies to a resource.
+func (c ContainerPaths) matches(apiVersion, kind string) bool {
+	if c.Kind != kind {
+		return false
+	}
+	if c.APIVersion == "" || c.APIVersion == apiVersion {
+		return true
+	}
+	group, _, _ := strings.Cut(apiVersion, "/")
+	return !strings.Contains(c.APIVersion, "/") && c.APIVersion == group
+}
+
+// containerPaths returns the container paths for a resource from the
+// configured entries, then the built-in ones, or nil for kinds with a
+// standard pod spec.
+func (c Config) containerPaths(apiVersion, kind string) []string {
+	for _, entries := range [][]ContainerPaths{c.ContainerPaths, builtinContainerPaths} {
+		for _, entry := range entries {
+			if entry.matches(apiVersion, kind) {
+				return entry.Paths
+			}
+		}
+	}
+	return nil
+}
+
+// injectEnvInPaths adds the injector's variables to the containers at
+// its paths.
+func (inj *envInjector) injectEnvInPaths(node *yaml.Node) {
+	for _, path := range inj.paths {
+		for _, container := range walkContainerPath(node, strings.Split(path, ".")) {
+			inj.injectEnvInContainer(container)
+		}
+	}
+}
+
+// walkContainerPath returns the container mappings at the path segments
+// below node.
+func walkContainerPath(node *yaml.Node, segments []string) []*yaml.Node {
+	if len(segments) == 0 {
+		if node.Kind == yaml.MappingNode {
+			return []*yaml.Node{node}
+		}
+		return nil
+	}
+
+	key, list := strings.CutSuffix(segments[0], "[]")
+	value := mappingValue(node, key)
+	if value == nil {
+		return nil
+	}
+	if !list {
+		return walkContainerPath(value, segments[1:])
+	}
+	if value.Kind != yaml.SequenceNode {
+		return nil
+	}
+	var containers []*yaml.Node
+	for _, item := range value.Content {
+		containers = append(containers, walkContainerPath(item, segments[1:])...)
+	}
+	return containers
+}
@@ -0,0 +1,154 @@
+package hook
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestProcess_ContainerPathsBuiltin(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  int
+	}{
+		{name: "argo workflow", input: `apiVersion: argoproj.io/v1alpha1
+kind: Workflow
+metadata:
+  name: migrate
+  annotations:
+    helm.sh/hook: pre-install
+spec:
+  entrypoint: main
+  templates:
+    - name: main
+      container:
+        image: busybox
+    - name: check
+      script:
+        image: python
+        source: print(1)
+      sidecars:
+        - name: proxy
+          image: envoy
+`, want: 3},
+		{name: "tekton taskrun", input: `apiVersion: tekton.dev/v1
+kind: TaskRun
+metadata:
+  name: migrate
+  annotations:
+    helm.sh/hook: pre-install
+spec:
+  taskSpec:
+    steps:
+      - name: migrate
+        image: busybox
+      - name: seed
+        image: busybox
+`, want: 2},
+		{name: "keda scaledjob", input: `apiVersion: keda.sh/v1alpha1
+kind: ScaledJob
+metadata:
+  name: migrate
+  annotations:
+    helm.sh/hook: pre-install
+spec:
+  jobTargetRef:
+    template:
+      spec:
+        containers:
+          - name: main
+            image: busybox
+`, want: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			output, err := Process([]byte(tt.input))
+			if err != nil {
+				t.Fatalf("Process failed: %v", err)
+			}
+			if got := strings.Count(string(output), "name: HELM_HOOK_EVENT"); got != tt.want {
+				t.Errorf("Expected %d injected containers, got %d:\n%s", tt.want, got, output)
+			}
+		})
+	}
+}
+
+func TestProcess_ContainerPathsConfigured(t *testing.T) {
+	input := `apiVersion: batch.example.com/v1
+kind: BatchRun
+metadata:
+  name: migrate
+  annotations:
+    helm.sh/hook: pre-install
+spec:
+  runner:
+    containers:
+      - name: main
+        image: busybox
+  template:
+    spec:
+      containers:
+        - name: ignored
+          image: busybox
+`
+
+	cfg := DefaultConfig()
+	cfg.ContainerPaths = []ContainerPaths{{APIVersion: "batch.example.com", Kind: "BatchRun", Paths: []string{"spec.runner.containers[]"}}}
+	output, err := NewProcessor(cfg).Process([]byte(input))
+	if err != nil {
+		t.Fatalf("Process failed: %v", err)
+	}
+	if got := strings.Count(string(output), "name: HELM_HOOK_EVENT"); got != 1 {
+		t.Errorf("Expected only spec.runner to be injected, got:\n%s", output)
+	}
+
+	// Entries for another group do not apply
+	cfg.ContainerPaths[0].APIVersion = "other.example.com/v1"
+	output, err = NewProcessor(cfg).Process([]byte(input))
+	if err != nil {
+		t.Fatalf("Process failed: %v", err)
+	}
+	if !strings.Contains(string(output), "- name: ignored\n          image: busybox\n          env:") {
+		t.Errorf("Expected the pod template to be injected, got:\n%s", output)
+	}
+}
+
+func TestProcess_ContainerPathsAnnotation(t *testing.T) {
+	input := `apiVersion: batch.example.com/v1
+kind: BatchRun
+metadata:
+  name: migrate
+  annotations:
+    helm.sh/hook: pre-install
+    helm.sh/hook-env-paths: "spec.runner.containers[], spec.finalizer"
+spec:
+  runner:
+    containers:
+      - name: main
+        image: busybox
+  finalizer:
+    image: busybox
+`
+
+	output, err := Process([]byte(input))
+	if err != nil {
+		t.Fatalf("Process failed: %v", err)
+	}
+	if got := strings.Count(string(output), "name: HELM_HOOK_EVENT"); got != 2 {
+		t.Errorf("Expected 2 injected containers, got:\n%s", output)
+	}
+	if strings.Contains(string(output), "hook-env-paths") {
+		t.Errorf("Expected helm.sh/hook-env-paths to be removed:\n%s", output)
+	}
+
+	_, err = Process([]byte(strings.Replace(input, "spec.finalizer", "spec..finalizer", 1)))
+	if err == nil || !strings.Contains(err.Error(), `helm.sh/hook-env-paths: invalid container path "spec..finalizer"`) {
+		t.Errorf("Expected invalid path error, got: %v", err)
+	}
+
+	_, err = Process([]byte(strings.Replace(input, "helm.sh/hook: pre-install", "helm.sh/hook: pre-install\n    helm.sh/hook-env-downward: hook", 1)))
+	if err == nil || !strings.Contains(err.Error(), "not supported for BatchRun") {
+		t.Errorf("Expected downward hook error, got: %v", err)
+	}
+}
@@ -0,0 +1,321 @@
+package hook
+
+import (
+	"fmt"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// dependencySeparator separates multiple dependencies of one hook event.
+// A comma cannot be used because it already separates hook events.
+const dependencySeparator = "|"
+
+// parseDependsOn parses helm.sh/hook-depends-on:
+// "pre-install=myapp-schema,post-upgrade=myapp-cache-warm|myapp-seed".
+// A name without an event applies to every hook of the resource.
+func parseDependsOn(value string, hooks []string) (map[string][]string, error) {
+	deps := make(map[string][]string)
+
+	isHook := make(map[string]bool)
+	for _, h := range hooks {
+		isHook[h] = true
+	}
+
+	for _, part := range strings.Split(value, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+
+		events := hooks
+		names := part
+		if kv := strings.SplitN(part, "=", 2); len(kv) == 2 {
+			hookName := strings.TrimSpace(kv[0])
+			if !isHook[hookName] {
+				return nil, fmt.Errorf("dependency specified for unknown hook %q", hookName)
+			}
+			events = []string{hookName}
+			names = kv[1]
+		}
+
+		for _, name := range strings.Split(names, dependencySeparator) {
+			name = strings.TrimSpace(name)
+			if name == "" {
+				return nil, fmt.Errorf("empty dependency in %q", part)
+			}
+			for _, h := range events {
+				deps[h] = append(deps[h], name)
+			}
+		}
+	}
+
+	if len(deps) == 0 {
+		return nil, fmt.Errorf("no dependencies found")
+	}
+	return deps, nil
+}
+
+// explicitWeights reports which hooks have a weight set by annotation
+// rather than the default.
+func (p *Processor) explicitWeights(annotations map[string]string, hooks []string) map[string]bool {
+	explicit := make(map[string]bool)
+
+	if value, ok := annotations[p.cfg.annotationKey(annotationHookWeights)]; ok {
+		isExplicit := strings.Contains(value, "=")
+		for i, part := range strings.Split(value, ",") {
+			part = strings.TrimSpace(part)
+			if part == "" {
+				continue
+			}
+			if isExplicit {
+				explicit[strings.TrimSpace(strings.SplitN(part, "=", 2)[0])] = true
+			} else if i < len(hooks) {
+				explicit[hooks[i]] = true
+			}
+		}
+		return explicit
+	}
+
+	if _, ok := annotations[annotationHookWeight]; ok {
+		for _, h := range hooks {
+			explicit[h] = true
+		}
+	}
+	return explicit
+}
+
+// resourceHooks returns the hook events of a resource, taken from
+// helm.sh/hook or, if absent, from the keys of helm.sh/hook-weights.
+func (p *Processor) resourceHooks(res *Resource) ([]string, error) {
+	if hookValue, ok := res.Annotations[annotationHook]; ok {
+		return parseHookEvents(hookValue), nil
+	}
+	if weightsValue, ok := res.Annotations[p.cfg.annotationKey(annotationHookWeights)]; ok {
+		return extractHooksFromWeights(weightsValue)
+	}
+	return nil, nil
+}
+
+// hookKey identifies a hook by event and original resource name.
+type hookKey struct {
+	event string
+	name  string
+}
+
+// dependentHook is a resource declaring helm.sh/hook-depends-on.
+type dependentHook struct {
+	// index is the 1-based position of the document in the input stream.
+	index    int
+	res      *Resource
+	node     *yaml.Node
+	hooks    []string
+	weights  map[string]int
+	explicit map[string]bool
+	deps     map[string][]string
+}
+
+// dependencyError is a dependency problem of a specific resource.
+type dependencyError struct {
+	index int
+	res   *Resource
+	err   error
+}
+
+func (e *dependencyError) Error() string {
+	return fmt.Sprintf("resource %q: %v", e.res.Name, e.err)
+}
+
+func (e *dependencyError) Unwrap() error {
+	return e.err
+}
+
+// dependencyResolver collects hook weights across documents and assigns
+// weights to the hooks that declare helm.sh/hook-depends-on.
+type dependencyResolver struct {
+	p *Processor
+	// known holds the weights of hooks without dependencies.
+	known   map[hookKey]int
+	pending []*dependentHook
+}
+
+// newDependencyResolver returns an empty resolver.
+func (p *Processor) newDependencyResolver() *dependencyResolver {
+	return &dependencyResolver{p: p, known: make(map[hookKey]int)}
+}
+
+// add records the hooks of a document. It returns true if the document
+// declares dependencies, in which case it must be processed after resolve.
+// Documents with invalid annotations are skipped; processing reports them.
+func (r *dependencyResolver) add(node *yaml.Node, index int) bool {
+	res, err := parseResource(node)
+	if err != nil {
+		return false
+	}
+
+	// Record each item of a List document
+	if items := listItems(node, res); items != nil {
+		deferred := false
+		for _, item := range items.Content {
+			if r.add(item, index) {
+				deferred = true
+			}
+		}
+		return deferred
+	}
+
+	hooks, err := r.p.resourceHooks(res)
+	if err != nil || len(hooks) == 0 {
+		return false
+	}
+	weights, err := r.p.parseWeights(res.Annotations, hooks)
+	if err != nil {
+		return false
+	}
+
+	// Skipped kinds keep their weights but their dependencies are ignored
+	value, ok := res.Annotations[r.p.cfg.annotationKey(annotationHookDependsOn)]
+	if !ok || !r.p.cfg.kindSelected(res.Kind) {
+		for _, h := range hooks {
+			key := hookKey{event: h, name: res.Name}
+			if w, seen := r.known[key]; !seen || weights[h] > w {
+				r.known[key] = weights[h]
+			}
+		}
+		return false
+	}
+
+	deps, err := parseDependsOn(value, hooks)
+	if err != nil {
+		return false
+	}
+
+	content := node
+	if node.Kind == yaml.DocumentNode && len(node.Content) > 0 {
+		content = node.Content[0]
+	}
+	r.pending = append(r.pending, &dependentHook{
+		index:    index,
+		res:      res,
+		node:     content,
+		hooks:    hooks,
+		weights:  weights,
+		explicit: r.p.explicitWeights(res.Annotations, hooks),
+		deps:     deps,
+	})
+	return true
+}
+
+// resolve assigns weights to every pending hook so that it runs after its
+// dependencies, then records them in helm.sh/hook-weights. Hooks are
+// visited depth-first per event, which orders them topologically. A hook
+// without an explicit weight gets one more than its heaviest dependency;
+// an explicit weight is kept and must already be greater.
+func (r *dependencyResolver) resolve() error {
+	if len(r.pending) == 0 {
+		return nil
+	}
+
+	pendingByKey := make(map[hookKey][]*dependentHook)
+	for _, d := range r.pending {
+		for _, h := range d.hooks {
+			key := hookKey{event: h, name: d.res.Name}
+			pendingByKey[key] = append(pendingByKey[key], d)
+		}
+	}
+
+	resolved := make(map[hookKey]int)
+	visiting := make(map[hookKey]bool)
+	var path []string
+
+	var visit func(key hookKey) (int, error)
+	visit = func(key hookKey) (int, error) {
+		if w, ok := resolved[key]; ok {
+			return w, nil
+		}
+
+		visiting[key] = true
+		path = append(path, key.name)
+		defer func() {
+			visiting[key] = false
+			path = path[:len(path)-1]
+		}()
+
+		weight, hasWeight := r.known[key]
+		for _, d := range pendingByKey[key] {
+			w := d.weights[key.event]
+
+			maxDep, hasDeps := 0, false
+			for _, dep := range d.deps[key.event] {
+				depKey := hookKey{event: key.event, name: dep}
+				if visiting[depKey] {
+					cycle := append(append([]string(nil), path[indexOf(path, dep):]...), dep)
+					return 0, &dependencyError{index: d.index, res: d.res,
+						err: fmt.Errorf("dependency cycle in %s: %s", key.event, strings.Join(cycle, " -> "))}
+				}
+				if _, ok := pendingByKey[depKey]; !ok {
+					if _, ok := r.known[depKey]; !ok {
+						return 0, &dependencyError{index: d.index, res: d.res,
+							err: fmt.Errorf("depends on unknown %s hook %q", key.event, dep)}
+					}
+				}
+				depWeight, err := visit(depKey)
+				if err != nil {
+					return 0, err
+				}
+				if !hasDeps || depWeight > maxDep {
+					maxDep, hasDeps = depWeight, true
+				}
+			}
+
+			if hasDeps {
+				if !d.explicit[key.event] {
+					w = maxDep + 1
+				} else if w <= maxDep {
+					return 0, &dependencyError{index: d.index, res: d.res,
+						err: fmt.Errorf("%s weight %d must be greater than its dependencies' weight %d", key.event, w, maxDep)}
+				}
+			}
+			d.weights[key.event] = w
+
+			if !hasWeight || w > weight {
+				weight, hasWeight = w, true
+			}
+		}
+
+		resolved[key] = weight
+		return weight, nil
+	}
+
+	for _, d := range r.pending {
+		for _, h := range d.hooks {
+			if _, err := visit(hookKey{event: h, name: d.res.Name}); err != nil {
+				return err
+			}
+		}
+	}
+
+	// Record the computed weights for processing
+	weightsKey := r.p.cfg.annotationKey(annotationHookWeights)
+	for _, d := range r.pending {
+		pairs := make([]string, len(d.hooks))
+		for i, h := range d.hooks {
+			pairs[i] = fmt.Sprintf("%s=%d", h, d.weights[h])
+		}
+		if err := setAnnotation(d.node, weightsKey, strings.Join(pairs, ",")); err != nil {
+			return &dependencyError{index: d.index, res: d.res, err: err}
+		}
+	}
+
+	return nil
+}
+
+// indexOf returns the position of value in list, or 0 if absent.
+func indexOf(list []string, value string) int {
+	for i, v := range list {
+		if v == value {
+			return i
+		}
+	}
+	return 0
+}
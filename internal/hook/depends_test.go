@@ -0,0 +1,197 @@
+package hook
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// dependsJob returns a Job manifest with the given name and extra annotations.
+func dependsJob(name, annotations string) string {
+	return `apiVersion: batch/v1
+kind: Job
+metadata:
+  name: ` + name + `
+  annotations:
+` + annotations + `spec:
+  template:
+    spec:
+      containers:
+        - name: main
+          image: busybox
+`
+}
+
+func TestProcess_DependsOnAssignsWeights(t *testing.T) {
+	input := dependsJob("myapp-migrate", `    helm.sh/hook: pre-install
+    helm.sh/hook-depends-on: "pre-install=myapp-schema"
+`) + "---\n" + dependsJob("myapp-seed", `    helm.sh/hook: pre-install,post-upgrade
+    helm.sh/hook-depends-on: "pre-install=myapp-migrate,post-upgrade=myapp-cache-warm"
+`) + "---\n" + dependsJob("myapp-schema", `    helm.sh/hook: pre-install
+    helm.sh/hook-weight: "-5"
+`) + "---\n" + dependsJob("myapp-cache-warm", `    helm.sh/hook: post-upgrade
+    helm.sh/hook-weight: "10"
+`)
+
+	plan, err := Plan([]byte(input), "install")
+	if err != nil {
+		t.Fatalf("Plan failed: %v", err)
+	}
+	want := []PlannedHook{
+		{Kind: "Job", Name: "myapp-schema", Weight: -5},
+		{Kind: "Job", Name: "myapp-migrate", Weight: -4},
+		{Kind: "Job", Name: "myapp-seed-pre-install", Weight: -3},
+	}
+	if got := plan.Phases[0].Hooks; !reflect.DeepEqual(got, want) {
+		t.Errorf("pre-install hooks = %v, want %v", got, want)
+	}
+
+	output, err := Process([]byte(input))
+	if err != nil {
+		t.Fatalf("Process failed: %v", err)
+	}
+	if strings.Contains(string(output), "hook-depends-on") || strings.Contains(string(output), "hook-weights") {
+		t.Errorf("dependency annotations should be removed after processing, got:\n%s", output)
+	}
+	if !strings.Contains(string(output), "name: myapp-seed-post-upgrade\n  annotations:\n    helm.sh/hook: \"post-upgrade\"\n    helm.sh/hook-weight: \"11\"") {
+		t.Errorf("Expected post-upgrade weight 11, got:\n%s", output)
+	}
+}
+
+func TestProcess_DependsOnKeepsOrder(t *testing.T) {
+	input := `apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: before
+---
+` + dependsJob("myapp-migrate", `    helm.sh/hook: pre-install
+    helm.sh/hook-depends-on: "pre-install=myapp-schema"
+`) + `---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: between
+---
+` + dependsJob("myapp-schema", `    helm.sh/hook: pre-install
+`)
+
+	output, err := Process([]byte(input))
+	if err != nil {
+		t.Fatalf("Process failed: %v", err)
+	}
+
+	last := -1
+	for _, name := range []string{"before", "myapp-migrate", "between", "myapp-schema"} {
+		i := strings.Index(string(output), "name: "+name+"\n")
+		if i < 0 || i < last {
+			t.Errorf("Expected %s in input order, got:\n%s", name, output)
+		}
+		last = i
+	}
+}
+
+func TestProcess_DependsOnExplicitWeight(t *testing.T) {
+	base := dependsJob("myapp-schema", `    helm.sh/hook: pre-install
+    helm.sh/hook-weight: "5"
+`)
+
+	valid := base + "---\n" + dependsJob("myapp-migrate", `    helm.sh/hook: pre-install
+    helm.sh/hook-weight: "20"
+    helm.sh/hook-depends-on: myapp-schema
+`)
+	output, err := Process([]byte(valid))
+	if err != nil {
+		t.Fatalf("Process failed: %v", err)
+	}
+	if !strings.Contains(string(output), `helm.sh/hook-weight: "20"`) {
+		t.Errorf("Expected explicit weight to be kept, got:\n%s", output)
+	}
+
+	invalid := base + "---\n" + dependsJob("myapp-migrate", `    helm.sh/hook: pre-install
+    helm.sh/hook-weight: "5"
+    helm.sh/hook-depends-on: myapp-schema
+`)
+	_, err = Process([]byte(invalid))
+	if err == nil || !strings.Contains(err.Error(), "must be greater") {
+		t.Errorf("Expected explicit weight conflict, got: %v", err)
+	}
+}
+
+func TestProcess_DependsOnErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{
+			name: "cycle",
+			input: dependsJob("a", `    helm.sh/hook: pre-install
+    helm.sh/hook-depends-on: b
+`) + "---\n" + dependsJob("b", `    helm.sh/hook: pre-install
+    helm.sh/hook-depends-on: a
+`),
+			want: "dependency cycle in pre-install: a -> b -> a",
+		},
+		{
+			name: "unknown dependency",
+			input: dependsJob("a", `    helm.sh/hook: pre-install
+    helm.sh/hook-depends-on: missing
+`),
+			want: `depends on unknown pre-install hook "missing"`,
+		},
+		{
+			name: "dependency in another event",
+			input: dependsJob("a", `    helm.sh/hook: pre-install
+    helm.sh/hook-depends-on: b
+`) + "---\n" + dependsJob("b", `    helm.sh/hook: post-install
+`),
+			want: `unknown pre-install hook "b"`,
+		},
+		{
+			name: "unknown hook",
+			input: dependsJob("a", `    helm.sh/hook: pre-install
+    helm.sh/hook-depends-on: "post-install=b"
+`),
+			want: `dependency specified for unknown hook "post-install"`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Process([]byte(tt.input))
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Expected error containing %q, got: %v", tt.want, err)
+			}
+		})
+	}
+}
+
+func TestLint_DependencyCycle(t *testing.T) {
+	input := dependsJob("a", `    helm.sh/hook: pre-install
+    helm.sh/hook-depends-on: b
+`) + "---\n" + dependsJob("b", `    helm.sh/hook: pre-install
+    helm.sh/hook-depends-on: a
+`)
+
+	problems := Lint([]byte(input))
+	if len(problems) != 1 {
+		t.Fatalf("Expected 1 problem, got %v", problems)
+	}
+	// The cycle is reported on the hook that closes it
+	if problems[0].Document != 2 || problems[0].Annotation != "helm.sh/hook-depends-on" {
+		t.Errorf("Expected problem on document 2 helm.sh/hook-depends-on, got %v", problems[0])
+	}
+}
+
+func TestParseDependsOn(t *testing.T) {
+	deps, err := parseDependsOn("pre-install=a|b, c", []string{"pre-install", "post-upgrade"})
+	if err != nil {
+		t.Fatalf("parseDependsOn failed: %v", err)
+	}
+	if got := strings.Join(deps["pre-install"], ","); got != "a,b,c" {
+		t.Errorf("pre-install dependencies = %q, want a,b,c", got)
+	}
+	if got := strings.Join(deps["post-upgrade"], ","); got != "c" {
+		t.Errorf("post-upgrade dependencies = %q, want c", got)
+	}
+}
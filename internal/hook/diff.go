@@ -0,0 +1,429 @@
+package hook
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Kinds of FieldChange.
+const (
+	ChangeAdded   = "added"
+	ChangeRemoved = "removed"
+	ChangeChanged = "changed"
+)
+
+// diffContext is the number of unchanged lines around each unified diff hunk.
+const diffContext = 3
+
+// plainPathKey matches mapping keys that need no quoting in a change path.
+var plainPathKey = regexp.MustCompile(`^[a-zA-Z0-9_-]+$`)
+
+// ManifestDiff describes how processing changed a manifest stream.
+// Only changed documents are included.
+type ManifestDiff struct {
+	Documents []DocumentDiff
+}
+
+// DocumentDiff describes how one input document was changed.
+type DocumentDiff struct {
+	// Document is the 1-based position of the document in the input.
+	Document int
+	Kind     string
+	Name     string
+	// Action is ActionEnhanced or ActionSplit.
+	Action string
+	// Original is the input document as read.
+	Original string
+	Outputs  []OutputDiff
+}
+
+// OutputDiff describes one document produced from an input document.
+type OutputDiff struct {
+	Kind string
+	Name string
+	// YAML is the output document as written.
+	YAML string
+	// Generated is set for resources created by helm-hooks, such as env
+	// ConfigMaps, which have no Changes.
+	Generated bool
+	Changes   []FieldChange
+}
+
+// FieldChange is a single difference between an input document and an output.
+type FieldChange struct {
+	// Path locates the field, e.g. metadata.annotations["helm.sh/hook"] or
+	// spec.template.spec.containers[main].env[HELM_HOOK_EVENT]. Items of
+	// lists whose entries all have a name are addressed by name.
+	Path string
+	// Kind is ChangeAdded, ChangeRemoved or ChangeChanged.
+	Kind string
+	// Old and New are the values before and after, as YAML.
+	Old string
+	New string
+}
+
+// Diff returns what processing input changes, using the default configuration.
+func Diff(input []byte) (*ManifestDiff, error) {
+	return defaultProcessor.Diff(input)
+}
+
+// Diff processes input the same way Process does and returns, for each
+// changed document, the fields changed in each of its outputs.
+func (p *Processor) Diff(input []byte) (*ManifestDiff, error) {
+	diff := &ManifestDiff{}
+	var diffErr error
+
+	diffing := *p
+	diffing.recorder = &reportRecorder{report: &Report{}}
+	diffing.observe = func(index int, raw []byte, processed []*yaml.Node) {
+		if processed == nil || diffErr != nil {
+			return
+		}
+		doc, err := diffDocument(index, raw, processed)
+		if err != nil {
+			diffErr = err
+			return
+		}
+		diff.Documents = append(diff.Documents, *doc)
+	}
+	if err := diffing.ProcessStream(context.Background(), bytes.NewReader(input), io.Discard); err != nil {
+		return nil, err
+	}
+	if diffErr != nil {
+		return nil, diffErr
+	}
+
+	actions := make(map[int]string)
+	for _, doc := range diffing.recorder.report.Documents {
+		actions[doc.Document] = doc.Action
+	}
+	for i := range diff.Documents {
+		diff.Documents[i].Action = actions[diff.Documents[i].Document]
+	}
+
+	// Documents with dependencies are processed last
+	sort.SliceStable(diff.Documents, func(i, j int) bool {
+		return diff.Documents[i].Document < diff.Documents[j].Document
+	})
+	return diff, nil
+}
+
+// diffDocument compares an input document with its outputs.
+func diffDocument(index int, raw []byte, processed []*yaml.Node) (*DocumentDiff, error) {
+	var original yaml.Node
+	if err := yaml.Unmarshal(raw, &original); err != nil {
+		return nil, fmt.Errorf("parsing YAML document %d: %w", index, err)
+	}
+	res, err := parseResource(&original)
+	if err != nil {
+		return nil, err
+	}
+
+	doc := &DocumentDiff{Document: index, Kind: res.Kind, Name: res.Name, Original: string(raw)}
+	indent := detectIndent(raw)
+	for _, out := range processed {
+		data, err := marshalNodeIndent(out, indent)
+		if err != nil {
+			return nil, err
+		}
+		outRes, err := parseResource(out)
+		if err != nil {
+			return nil, err
+		}
+
+		output := OutputDiff{Kind: outRes.Kind, Name: outRes.Name, YAML: string(data)}
+		if outRes.Kind == res.Kind {
+			diffNodes("", &original, out, &output.Changes)
+		} else {
+			output.Generated = true
+		}
+		doc.Outputs = append(doc.Outputs, output)
+	}
+	return doc, nil
+}
+
+// diffNodes appends the changes between a and b, found at path, to changes.
+func diffNodes(path string, a, b *yaml.Node, changes *[]FieldChange) {
+	if a.Kind == yaml.DocumentNode && len(a.Content) > 0 {
+		a = a.Content[0]
+	}
+	if b.Kind == yaml.DocumentNode && len(b.Content) > 0 {
+		b = b.Content[0]
+	}
+
+	switch {
+	case a.Kind == yaml.MappingNode && b.Kind == yaml.MappingNode:
+		for i := 0; i < len(a.Content); i += 2 {
+			key := a.Content[i].Value
+			childPath := joinPathKey(path, key)
+			if other := mappingValue(b, key); other != nil {
+				diffNodes(childPath, a.Content[i+1], other, changes)
+			} else {
+				*changes = append(*changes, FieldChange{Path: childPath, Kind: ChangeRemoved, Old: renderDiffValue(a.Content[i+1])})
+			}
+		}
+		for i := 0; i < len(b.Content); i += 2 {
+			key := b.Content[i].Value
+			if mappingValue(a, key) == nil {
+				*changes = append(*changes, FieldChange{Path: joinPathKey(path, key), Kind: ChangeAdded, New: renderDiffValue(b.Content[i+1])})
+			}
+		}
+
+	case a.Kind == yaml.SequenceNode && b.Kind == yaml.SequenceNode && namedItems(a) && namedItems(b):
+		for _, item := range a.Content {
+			name := mappingValue(item, "name").Value
+			childPath := path + "[" + name + "]"
+			if other := namedItem(b, name); other != nil {
+				diffNodes(childPath, item, other, changes)
+			} else {
+				*changes = append(*changes, FieldChange{Path: childPath, Kind: ChangeRemoved, Old: renderDiffValue(item)})
+			}
+		}
+		for _, item := range b.Content {
+			name := mappingValue(item, "name").Value
+			if namedItem(a, name) == nil {
+				*changes = append(*changes, FieldChange{Path: path + "[" + name + "]", Kind: ChangeAdded, New: renderDiffValue(item)})
+			}
+		}
+
+	case a.Kind == yaml.SequenceNode && b.Kind == yaml.SequenceNode:
+		for i := 0; i < len(a.Content) || i < len(b.Content); i++ {
+			childPath := path + "[" + strconv.Itoa(i) + "]"
+			switch {
+			case i >= len(b.Content):
+				*changes = append(*changes, FieldChange{Path: childPath, Kind: ChangeRemoved, Old: renderDiffValue(a.Content[i])})
+			case i >= len(a.Content):
+				*changes = append(*changes, FieldChange{Path: childPath, Kind: ChangeAdded, New: renderDiffValue(b.Content[i])})
+			default:
+				diffNodes(childPath, a.Content[i], b.Content[i], changes)
+			}
+		}
+
+	default:
+		if !nodesEqual(a, b) {
+			*changes = append(*changes, FieldChange{Path: path, Kind: ChangeChanged, Old: renderDiffValue(a), New: renderDiffValue(b)})
+		}
+	}
+}
+
+// namedItem returns the item of a sequence with the given name field, or nil.
+func namedItem(seq *yaml.Node, name string) *yaml.Node {
+	for _, item := range seq.Content {
+		if v := mappingValue(item, "name"); v != nil && v.Value == name {
+			return item
+		}
+	}
+	return nil
+}
+
+// joinPathKey appends a mapping key to a change path, quoting keys such
+// as annotation names that contain dots or slashes.
+func joinPathKey(path, key string) string {
+	if !plainPathKey.MatchString(key) {
+		return path + "[" + strconv.Quote(key) + "]"
+	}
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+// renderDiffValue formats a node as single-line YAML.
+func renderDiffValue(node *yaml.Node) string {
+	if node.Kind == yaml.ScalarNode {
+		return node.Value
+	}
+	flow := copyNode(node)
+	flow.Style = yaml.FlowStyle
+	data, err := yaml.Marshal(flow)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(data))
+}
+
+// WriteText writes the field changes of every changed document to w.
+// Split documents are shown as "split 1 → N" with the changes of each clone.
+func (d *ManifestDiff) WriteText(w io.Writer) error {
+	var buf bytes.Buffer
+	for i, doc := range d.Documents {
+		if i > 0 {
+			fmt.Fprintln(&buf)
+		}
+		action := doc.Action
+		if action == ActionSplit {
+			splits := 0
+			for _, out := range doc.Outputs {
+				if !out.Generated {
+					splits++
+				}
+			}
+			action = fmt.Sprintf("split 1 → %d", splits)
+		}
+		fmt.Fprintf(&buf, "document %d: %s/%s (%s)\n", doc.Document, doc.Kind, doc.Name, action)
+
+		for _, out := range doc.Outputs {
+			if out.Generated {
+				fmt.Fprintf(&buf, "  + %s/%s (generated)\n", out.Kind, out.Name)
+				continue
+			}
+			fmt.Fprintf(&buf, "  %s/%s\n", out.Kind, out.Name)
+			for _, c := range out.Changes {
+				switch c.Kind {
+				case ChangeAdded:
+					fmt.Fprintf(&buf, "    + %s: %s\n", c.Path, c.New)
+				case ChangeRemoved:
+					fmt.Fprintf(&buf, "    - %s: %s\n", c.Path, c.Old)
+				default:
+					fmt.Fprintf(&buf, "    ~ %s: %s → %s\n", c.Path, c.Old, c.New)
+				}
+			}
+		}
+	}
+
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+// WriteUnified writes a unified diff between every changed document and
+// its outputs, joined with document separators, to w.
+func (d *ManifestDiff) WriteUnified(w io.Writer) error {
+	var buf bytes.Buffer
+	for _, doc := range d.Documents {
+		var outputs []string
+		var names []string
+		for _, out := range doc.Outputs {
+			outputs = append(outputs, out.YAML)
+			names = append(names, out.Kind+"/"+out.Name)
+		}
+
+		fmt.Fprintf(&buf, "--- document %d: %s/%s\n", doc.Document, doc.Kind, doc.Name)
+		fmt.Fprintf(&buf, "+++ document %d: %s\n", doc.Document, strings.Join(names, ", "))
+		writeHunks(&buf, diffLines(splitLines(doc.Original), splitLines(strings.Join(outputs, "---\n"))))
+	}
+
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+// splitLines splits text into lines without their line endings.
+func splitLines(text string) []string {
+	text = strings.TrimSuffix(text, "\n")
+	if text == "" {
+		return nil
+	}
+	return strings.Split(text, "\n")
+}
+
+// diffLines returns the edit script turning a into b as lines prefixed
+// with ' ' (kept), '-' (removed) or '+' (added), using the longest
+// common subsequence of lines.
+func diffLines(a, b []string) []string {
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else if lcs[i+1][j] >= lcs[i][j+1] {
+				lcs[i][j] = lcs[i+1][j]
+			} else {
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+
+	var ops []string
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		switch {
+		case i < len(a) && j < len(b) && a[i] == b[j]:
+			ops = append(ops, " "+a[i])
+			i++
+			j++
+		case i < len(a) && (j == len(b) || lcs[i+1][j] >= lcs[i][j+1]):
+			ops = append(ops, "-"+a[i])
+			i++
+		default:
+			ops = append(ops, "+"+b[j])
+			j++
+		}
+	}
+	return ops
+}
+
+// writeHunks writes an edit script from diffLines as unified diff hunks
+// with diffContext lines of context.
+func writeHunks(buf *bytes.Buffer, ops []string) {
+	for start := 0; start < len(ops); {
+		// Find the next change
+		first := start
+		for first < len(ops) && ops[first][0] == ' ' {
+			first++
+		}
+		if first == len(ops) {
+			return
+		}
+
+		// Extend the hunk while changes are close enough to share context
+		last := first
+		for k := first; k < len(ops); k++ {
+			if ops[k][0] != ' ' {
+				last = k
+			} else if k-last > 2*diffContext {
+				break
+			}
+		}
+
+		from := first - diffContext
+		if from < start {
+			from = start
+		}
+		to := last + diffContext + 1
+		if to > len(ops) {
+			to = len(ops)
+		}
+
+		// Line numbers of the hunk in the old and new text
+		oldLine, newLine := 1, 1
+		for _, op := range ops[:from] {
+			if op[0] != '+' {
+				oldLine++
+			}
+			if op[0] != '-' {
+				newLine++
+			}
+		}
+		oldCount, newCount := 0, 0
+		for _, op := range ops[from:to] {
+			if op[0] != '+' {
+				oldCount++
+			}
+			if op[0] != '-' {
+				newCount++
+			}
+		}
+		if oldCount == 0 {
+			oldLine--
+		}
+		if newCount == 0 {
+			newLine--
+		}
+
+		fmt.Fprintf(buf, "@@ -%d,%d +%d,%d @@\n", oldLine, oldCount, newLine, newCount)
+		for _, op := range ops[from:to] {
+			buf.WriteString(op + "\n")
+		}
+		start = to
+	}
+}
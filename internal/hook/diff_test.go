@@ -0,0 +1,152 @@
+package hook
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestDiff_Split(t *testing.T) {
+	input := `apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: config
+---
+` + dependsJob("myapp", `    helm.sh/hook: pre-install,post-upgrade
+    helm.sh/hook-weights: "-5,10"
+`)
+
+	diff, err := Diff([]byte(input))
+	if err != nil {
+		t.Fatalf("Diff failed: %v", err)
+	}
+
+	// Unchanged documents are not included
+	if len(diff.Documents) != 1 {
+		t.Fatalf("Expected 1 changed document, got %d", len(diff.Documents))
+	}
+	doc := diff.Documents[0]
+	if doc.Document != 2 || doc.Action != ActionSplit || len(doc.Outputs) != 2 {
+		t.Fatalf("Unexpected document diff: %+v", doc)
+	}
+
+	want := []FieldChange{
+		{Path: "metadata.name", Kind: ChangeChanged, Old: "myapp", New: "myapp-pre-install"},
+		{Path: `metadata.annotations["helm.sh/hook"]`, Kind: ChangeChanged, Old: "pre-install,post-upgrade", New: "pre-install"},
+		{Path: `metadata.annotations["helm.sh/hook-weights"]`, Kind: ChangeRemoved, Old: "-5,10"},
+		{Path: `metadata.annotations["helm.sh/hook-weight"]`, Kind: ChangeAdded, New: "-5"},
+		{Path: "spec.template.spec.containers[main].env", Kind: ChangeAdded, New: `[{name: HELM_HOOK_EVENT, value: "pre-install"}, {name: HELM_HOOK_WEIGHT, value: "-5"}]`},
+	}
+	if got := doc.Outputs[0].Changes; !reflect.DeepEqual(got, want) {
+		t.Errorf("changes = %+v\nwant %+v", got, want)
+	}
+
+	var buf bytes.Buffer
+	if err := diff.WriteText(&buf); err != nil {
+		t.Fatalf("WriteText failed: %v", err)
+	}
+	if !strings.HasPrefix(buf.String(), "document 2: Job/myapp (split 1 → 2)\n  Job/myapp-pre-install\n    ~ metadata.name: myapp → myapp-pre-install\n") {
+		t.Errorf("Unexpected text output:\n%s", buf.String())
+	}
+}
+
+func TestDiff_EnvEntries(t *testing.T) {
+	input := `apiVersion: v1
+kind: Pod
+metadata:
+  name: seed
+  annotations:
+    helm.sh/hook: post-install
+spec:
+  containers:
+    - name: main
+      image: busybox
+      env:
+        - name: HELM_HOOK_EVENT
+          value: old
+`
+
+	diff, err := Diff([]byte(input))
+	if err != nil {
+		t.Fatalf("Diff failed: %v", err)
+	}
+
+	want := []FieldChange{
+		{Path: "spec.containers[main].env[HELM_HOOK_EVENT].value", Kind: ChangeChanged, Old: "old", New: "post-install"},
+		{Path: "spec.containers[main].env[HELM_HOOK_WEIGHT]", Kind: ChangeAdded, New: `{name: HELM_HOOK_WEIGHT, value: "0"}`},
+	}
+	if got := diff.Documents[0].Outputs[0].Changes; !reflect.DeepEqual(got, want) {
+		t.Errorf("changes = %+v\nwant %+v", got, want)
+	}
+	if diff.Documents[0].Action != ActionEnhanced {
+		t.Errorf("Expected enhanced, got %q", diff.Documents[0].Action)
+	}
+}
+
+func TestDiff_GeneratedConfigMap(t *testing.T) {
+	input := dependsJob("migrate", `    helm.sh/hook: pre-install
+    helm.sh/hook-env-mode: envFrom
+`)
+
+	diff, err := Diff([]byte(input))
+	if err != nil {
+		t.Fatalf("Diff failed: %v", err)
+	}
+
+	outputs := diff.Documents[0].Outputs
+	if len(outputs) != 2 || !outputs[0].Generated || outputs[0].Changes != nil || outputs[1].Generated {
+		t.Fatalf("Unexpected outputs: %+v", outputs)
+	}
+
+	var buf bytes.Buffer
+	if err := diff.WriteText(&buf); err != nil {
+		t.Fatalf("WriteText failed: %v", err)
+	}
+	if !strings.Contains(buf.String(), "  + ConfigMap/migrate-env (generated)\n  Job/migrate\n") {
+		t.Errorf("Unexpected text output:\n%s", buf.String())
+	}
+}
+
+func TestDiff_WriteUnified(t *testing.T) {
+	input := dependsJob("myapp", `    helm.sh/hook: pre-install
+    helm.sh/hook-weights: "pre-install=3"
+`)
+
+	cfg := DefaultConfig()
+	cfg.EnvDefault = false
+	diff, err := NewProcessor(cfg).Diff([]byte(input))
+	if err != nil {
+		t.Fatalf("Diff failed: %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := diff.WriteUnified(&buf); err != nil {
+		t.Fatalf("WriteUnified failed: %v", err)
+	}
+	want := `--- document 1: Job/myapp
++++ document 1: Job/myapp
+@@ -3,8 +3,8 @@
+ metadata:
+   name: myapp
+   annotations:
+-    helm.sh/hook: pre-install
+-    helm.sh/hook-weights: "pre-install=3"
++    helm.sh/hook: "pre-install"
++    helm.sh/hook-weight: "3"
+ spec:
+   template:
+     spec:
+`
+	if buf.String() != want {
+		t.Errorf("unified diff =\n%s\nwant\n%s", buf.String(), want)
+	}
+}
+
+func TestDiffLines(t *testing.T) {
+	got := diffLines([]string{"a", "b", "c"}, []string{"a", "x", "c", "d"})
+	want := []string{" a", "-b", "+x", " c", "+d"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("diffLines = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,686 @@
+package hook
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Default names of the built-in environment variables injected into
+// every hook container. Config.EventEnvVar and Config.WeightEnvVar
+// replace them.
+const (
+	envHookEvent  = "HELM_HOOK_EVENT"
+	envHookWeight = "HELM_HOOK_WEIGHT"
+)
+
+// Release context variables, injected when Config.Release sets them.
+const (
+	envReleaseName      = "HELM_RELEASE_NAME"
+	envReleaseNamespace = "HELM_RELEASE_NAMESPACE"
+	envReleaseRevision  = "HELM_RELEASE_REVISION"
+	envChartName        = "HELM_CHART_NAME"
+	envChartVersion     = "HELM_CHART_VERSION"
+)
+
+// Env modes, selected by Config.EnvMode and helm.sh/hook-env-mode.
+const (
+	// EnvModeEnv adds the variables to each container's env (default).
+	EnvModeEnv = "env"
+	// EnvModeEnvFrom puts the variables in a generated ConfigMap that
+	// each container references with envFrom.
+	EnvModeEnvFrom = "envFrom"
+)
+
+// envConfigMapSuffix is appended to a hook's name to name its env ConfigMap.
+const envConfigMapSuffix = "env"
+
+// Downward API sources, selected by Config.DownwardEnv and
+// helm.sh/hook-env-downward.
+const (
+	// downwardPod injects the pod name, namespace and node.
+	downwardPod = "pod"
+	// downwardHook reads the hook event and weight from pod annotations
+	// instead of literal values.
+	downwardHook = "hook"
+)
+
+// downwardPodVars are injected for the "pod" source. The container's own
+// definitions take precedence.
+var downwardPodVars = []envVar{
+	{Name: "POD_NAME", FieldPath: "metadata.name", Optional: true},
+	{Name: "POD_NAMESPACE", FieldPath: "metadata.namespace", Optional: true},
+	{Name: "NODE_NAME", FieldPath: "spec.nodeName", Optional: true},
+}
+
+// envVarNamePattern matches the environment variable names Kubernetes accepts.
+var envVarNamePattern = regexp.MustCompile(`^[-._a-zA-Z][-._a-zA-Z0-9]*$`)
+
+// envVar is a single environment variable injected into hook containers.
+type envVar struct {
+	Name  string
+	Value string
+	// FieldPath, if set, reads the value from the pod with a fieldRef.
+	FieldPath string
+	// Optional variables are only added to containers that do not
+	// define them.
+	Optional bool
+}
+
+// envNames holds the names of the built-in variables for one resource.
+type envNames struct {
+	event  string
+	weight string
+	// release holds the release context variables that are injected.
+	release []envVar
+	// downward holds the Downward API variables that are injected.
+	downward []envVar
+	// fieldRef reads event and weight from the pod's hook annotations.
+	fieldRef bool
+	// paths, if set, locates the containers instead of the pod spec.
+	paths []string
+	// ephemeral also injects into ephemeralContainers.
+	ephemeral bool
+	// envFrom moves literal variables to a generated ConfigMap.
+	envFrom bool
+	// filter, if set, selects the containers by name.
+	filter *containerFilter
+}
+
+// reserved reports whether a custom variable would override a built-in one.
+func (n envNames) reserved(name string) bool {
+	if name == n.event || name == n.weight {
+		return true
+	}
+	for _, v := range append(n.release, n.downward...) {
+		if v.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// setDownward selects the Downward API sources in values.
+func (n *envNames) setDownward(values []string) error {
+	n.downward, n.fieldRef = nil, false
+	for _, v := range values {
+		switch strings.TrimSpace(v) {
+		case "":
+		case downwardPod:
+			n.downward = downwardPodVars
+		case downwardHook:
+			n.fieldRef = true
+		default:
+			return fmt.Errorf("unknown downward env source %q, expected %s or %s", strings.TrimSpace(v), downwardPod, downwardHook)
+		}
+	}
+	return nil
+}
+
+// annotationFieldPath returns the fieldRef path of a pod annotation.
+func annotationFieldPath(key string) string {
+	return "metadata.annotations['" + key + "']"
+}
+
+// envVars returns the release context variables for the non-empty fields.
+func (r Release) envVars() []envVar {
+	var vars []envVar
+	for _, v := range []envVar{
+		{Name: envReleaseName, Value: r.Name},
+		{Name: envReleaseNamespace, Value: r.Namespace},
+		{Name: envReleaseRevision, Value: r.Revision},
+		{Name: envChartName, Value: r.Chart},
+		{Name: envChartVersion, Value: r.ChartVersion},
+	} {
+		if v.Value != "" {
+			vars = append(vars, v)
+		}
+	}
+	return vars
+}
+
+// hookSpec holds everything parsed for one hook event of a resource.
+type hookSpec struct {
+	event        string
+	weight       int
+	deletePolicy string
+	// names are the names of the built-in variables.
+	names envNames
+	// env holds the custom variables for this event, in annotation order.
+	env []envVar
+	// patch, if set, is applied to this event's copy of the resource.
+	patch *hookPatch
+}
+
+// envInjector adds a fixed set of variables to every container of a resource.
+type envInjector struct {
+	vars []envVar
+	// paths, if set, locates the containers; see ContainerPaths.
+	paths []string
+	// ephemeral also injects into ephemeralContainers.
+	ephemeral bool
+	// configMap, if set, is referenced with envFrom by every container.
+	configMap string
+	// configMapRefs counts the containers given the configMap reference,
+	// named or not.
+	configMapRefs int
+	// filter, if set, selects the containers injected into.
+	filter *containerFilter
+	// podAnnotations, if set, are added to the pod metadata so that
+	// fieldRef variables can read them.
+	podAnnotations []envVar
+	// containers collects the names of the containers injected into.
+	containers []string
+}
+
+// newEnvInjector returns an injector for the built-in variables of spec,
+// then the release context, the Downward API variables and its custom
+// variables.
+func newEnvInjector(spec hookSpec) *envInjector {
+	inj := &envInjector{paths: spec.names.paths, ephemeral: spec.names.ephemeral, filter: spec.names.filter}
+	event := envVar{Name: spec.names.event, Value: spec.event}
+	weight := envVar{Name: spec.names.weight, Value: strconv.Itoa(spec.weight)}
+	if spec.names.fieldRef {
+		inj.podAnnotations = []envVar{
+			{Name: annotationHook, Value: event.Value},
+			{Name: annotationHookWeight, Value: weight.Value},
+		}
+		event = envVar{Name: event.Name, FieldPath: annotationFieldPath(annotationHook)}
+		weight = envVar{Name: weight.Name, FieldPath: annotationFieldPath(annotationHookWeight)}
+	}
+	inj.vars = append([]envVar{event, weight}, spec.names.release...)
+	inj.vars = append(inj.vars, spec.names.downward...)
+	inj.vars = append(inj.vars, spec.env...)
+	return inj
+}
+
+// useConfigMap makes the injector reference the named ConfigMap with
+// envFrom and returns the literal variables it must hold. fieldRef
+// variables stay in env.
+func (inj *envInjector) useConfigMap(name string) []envVar {
+	var data, vars []envVar
+	for _, v := range inj.vars {
+		if v.FieldPath == "" {
+			data = append(data, v)
+		} else {
+			vars = append(vars, v)
+		}
+	}
+	inj.vars, inj.configMap = vars, name
+	return data
+}
+
+// injectEnv injects the variables of spec into the containers of
+// content, the hook resource named name. It returns the names of the
+// containers and, in envFrom mode, the generated ConfigMap.
+func (p *Processor) injectEnv(content *yaml.Node, spec hookSpec, name string) ([]string, *yaml.Node, error) {
+	inj := newEnvInjector(spec)
+	var configMap *yaml.Node
+	if spec.names.envFrom {
+		rule, err := p.cfg.nameRule("ConfigMap")
+		if err != nil {
+			return nil, nil, err
+		}
+		configMapName, _, err := generateName(name, envConfigMapSuffix, rule)
+		if err != nil {
+			return nil, nil, fmt.Errorf("env ConfigMap: %w", err)
+		}
+		data := inj.useConfigMap(configMapName)
+		if err := inj.injectEnvVars(content); err != nil {
+			return nil, nil, err
+		}
+		// Nothing references the ConfigMap when no container was selected
+		if inj.configMapRefs > 0 {
+			configMap = envConfigMap(content, configMapName, spec, data)
+			p.generated.add(configMap)
+			p.recorder.addOutput(OutputReport{Name: configMapName, Event: spec.event, Weight: spec.weight - 1, Generated: true})
+		}
+		return inj.containers, configMap, nil
+	}
+	if err := inj.injectEnvVars(content); err != nil {
+		return nil, nil, err
+	}
+	return inj.containers, configMap, nil
+}
+
+// envConfigMap returns the ConfigMap holding the variables of a hook in
+// envFrom mode. It is a hook of the same event with a lower weight, so it
+// exists before the hook's pods start, and shares the hook's delete policy.
+func envConfigMap(content *yaml.Node, name string, spec hookSpec, data []envVar) *yaml.Node {
+	hookMetadata := mappingValue(content, "metadata")
+
+	annotations := &yaml.Node{Kind: yaml.MappingNode}
+	setAnnotationValue(annotations, annotationHook, spec.event)
+	setAnnotationValue(annotations, annotationHookWeight, strconv.Itoa(spec.weight-1))
+	if policy := mappingValue(mappingValue(hookMetadata, "annotations"), annotationHookDeletePolicy); policy != nil {
+		setAnnotationValue(annotations, annotationHookDeletePolicy, policy.Value)
+	}
+
+	metadata := &yaml.Node{Kind: yaml.MappingNode}
+	setMappingValue(metadata, "name", &yaml.Node{Kind: yaml.ScalarNode, Value: name})
+	if namespace := mappingValue(hookMetadata, "namespace"); namespace != nil {
+		setMappingValue(metadata, "namespace", &yaml.Node{Kind: yaml.ScalarNode, Value: namespace.Value})
+	}
+	setMappingValue(metadata, "annotations", annotations)
+
+	values := &yaml.Node{Kind: yaml.MappingNode}
+	for _, v := range data {
+		setMappingValue(values, v.Name, envValueNode(v))
+	}
+
+	configMap := &yaml.Node{Kind: yaml.MappingNode}
+	setMappingValue(configMap, "apiVersion", &yaml.Node{Kind: yaml.ScalarNode, Value: "v1"})
+	setMappingValue(configMap, "kind", &yaml.Node{Kind: yaml.ScalarNode, Value: "ConfigMap"})
+	setMappingValue(configMap, "metadata", metadata)
+	setMappingValue(configMap, "data", values)
+	return &yaml.Node{Kind: yaml.DocumentNode, Content: []*yaml.Node{configMap}}
+}
+
+// eventEnvKey returns the per-event env annotation key, e.g. helm.sh/hook-env-pre-install.
+func (p *Processor) eventEnvKey(hookEvent string) string {
+	return p.cfg.annotationKey(annotationHookEnv) + "-" + hookEvent
+}
+
+// envVarAnnotations returns every custom env annotation key the processor
+// recognises, so they can be removed once processed.
+func (p *Processor) envVarAnnotations() []string {
+	keys := []string{
+		p.cfg.annotationKey(annotationHookEnvVars),
+		p.cfg.annotationKey(annotationHookEnvNames),
+		p.cfg.annotationKey(annotationHookEnvPrefix),
+		p.cfg.annotationKey(annotationHookEnvDownward),
+		p.cfg.annotationKey(annotationHookEnvPaths),
+		p.cfg.annotationKey(annotationHookEnvMode),
+		p.cfg.annotationKey(annotationHookEnvContainers),
+		p.cfg.annotationKey(annotationHookEnvExcludeContainers),
+	}
+	for _, h := range sortedValidHooks() {
+		keys = append(keys, p.eventEnvKey(h))
+	}
+	return keys
+}
+
+// sortedValidHooks returns the valid hook events in a stable order.
+func sortedValidHooks() []string {
+	hooks := make([]string, 0, len(validHooks))
+	for h := range validHooks {
+		hooks = append(hooks, h)
+	}
+	sort.Strings(hooks)
+	return hooks
+}
+
+// hasHookEnvVars reports whether a resource declares custom env vars or
+// built-in variable names.
+func (p *Processor) hasHookEnvVars(res *Resource) bool {
+	for _, key := range p.envVarAnnotations() {
+		if _, ok := res.Annotations[key]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+// envNames returns the names of the built-in variables for a resource.
+// helm.sh/hook-env-names ("event=HOOK_PHASE,weight=HOOK_WEIGHT") overrides
+// the configured names, and helm.sh/hook-env-prefix the configured
+// prefix, which is prepended to both. helm.sh/hook-env-downward
+// ("pod,hook") overrides the configured Downward API sources,
+// helm.sh/hook-env-paths the container paths of the kind, and
+// helm.sh/hook-env-mode the env mode. helm.sh/hook-env-containers and
+// helm.sh/hook-env-exclude-containers select containers by name.
+func (p *Processor) envNames(res *Resource) (envNames, error) {
+	names := envNames{event: p.cfg.eventEnvVar(), weight: p.cfg.weightEnvVar(), release: p.cfg.Release.envVars()}
+
+	namesKey := p.cfg.annotationKey(annotationHookEnvNames)
+	if value, ok := res.Annotations[namesKey]; ok {
+		for _, pair := range strings.Split(value, ",") {
+			pair = strings.TrimSpace(pair)
+			if pair == "" {
+				continue
+			}
+			kv := strings.SplitN(pair, "=", 2)
+			if len(kv) != 2 {
+				return names, annotationErrorf(namesKey, "invalid env name mapping %q, expected format event=NAME or weight=NAME", pair)
+			}
+			name := strings.TrimSpace(kv[1])
+			if !envVarNamePattern.MatchString(name) {
+				return names, annotationErrorf(namesKey, "invalid env var name %q", name)
+			}
+			switch strings.TrimSpace(kv[0]) {
+			case "event":
+				names.event = name
+			case "weight":
+				names.weight = name
+			default:
+				return names, annotationErrorf(namesKey, "unknown built-in env var %q, expected event or weight", strings.TrimSpace(kv[0]))
+			}
+		}
+		if names.event == names.weight {
+			return names, annotationErrorf(namesKey, "event and weight use the same name %q", names.event)
+		}
+	}
+
+	prefix := p.cfg.EnvVarPrefix
+	prefixKey := p.cfg.annotationKey(annotationHookEnvPrefix)
+	if value, ok := res.Annotations[prefixKey]; ok {
+		prefix = strings.TrimSpace(value)
+		if prefix != "" && !envVarNamePattern.MatchString(prefix) {
+			return names, annotationErrorf(prefixKey, "invalid env var prefix %q", prefix)
+		}
+	}
+	names.event = prefix + names.event
+	names.weight = prefix + names.weight
+
+	if err := names.setDownward(p.cfg.DownwardEnv); err != nil {
+		return names, err
+	}
+	downwardKey := p.cfg.annotationKey(annotationHookEnvDownward)
+	if value, ok := res.Annotations[downwardKey]; ok {
+		if err := names.setDownward(strings.Split(value, ",")); err != nil {
+			return names, &annotationError{annotation: downwardKey, err: err}
+		}
+	}
+	// The argocd profile removes the hook annotations the fieldRefs read
+	if names.fieldRef && p.cfg.Profile == ProfileArgoCD && res.Kind == "Pod" {
+		return names, fmt.Errorf("the %s downward env source needs the %s profile for Pods", downwardHook, ProfileHelm)
+	}
+
+	names.paths = p.cfg.containerPaths(res.APIVersion, res.Kind)
+	if err := validateContainerPaths(names.paths); err != nil {
+		return names, err
+	}
+	pathsKey := p.cfg.annotationKey(annotationHookEnvPaths)
+	if value, ok := res.Annotations[pathsKey]; ok {
+		names.paths = nil
+		for _, path := range strings.Split(value, ",") {
+			if path = strings.TrimSpace(path); path != "" {
+				names.paths = append(names.paths, path)
+			}
+		}
+		if err := validateContainerPaths(names.paths); err != nil {
+			return names, &annotationError{annotation: pathsKey, err: err}
+		}
+	}
+	names.ephemeral = p.cfg.EnvEphemeralContainers
+	names.envFrom = p.cfg.EnvMode == EnvModeEnvFrom
+	if p.cfg.EnvMode != "" && !validEnvMode(p.cfg.EnvMode) {
+		return names, fmt.Errorf("unknown env mode %q", p.cfg.EnvMode)
+	}
+	modeKey := p.cfg.annotationKey(annotationHookEnvMode)
+	if value, ok := res.Annotations[modeKey]; ok {
+		value = strings.TrimSpace(value)
+		if !validEnvMode(value) {
+			return names, annotationErrorf(modeKey, "unknown env mode %q, expected %s or %s", value, EnvModeEnv, EnvModeEnvFrom)
+		}
+		names.envFrom = value == EnvModeEnvFrom
+	}
+
+	includeKey := p.cfg.annotationKey(annotationHookEnvContainers)
+	excludeKey := p.cfg.annotationKey(annotationHookEnvExcludeContainers)
+	include, err := containerPatterns(res, includeKey)
+	if err != nil {
+		return names, err
+	}
+	exclude, err := containerPatterns(res, excludeKey)
+	if err != nil {
+		return names, err
+	}
+	if include != nil || exclude != nil {
+		names.filter = &containerFilter{include: include, exclude: exclude, matched: make(map[string]bool)}
+	}
+
+	// Container paths do not locate the pod metadata
+	if names.fieldRef && len(names.paths) > 0 {
+		return names, fmt.Errorf("the %s downward env source is not supported for %s, whose containers are set by path", downwardHook, res.Kind)
+	}
+
+	// Catch invalid names from the global configuration
+	for _, name := range []string{names.event, names.weight} {
+		if !envVarNamePattern.MatchString(name) {
+			return names, fmt.Errorf("invalid env var name %q", name)
+		}
+	}
+	if names.event == names.weight {
+		return names, fmt.Errorf("event and weight env vars use the same name %q", names.event)
+	}
+	for _, v := range names.release {
+		if v.Name == names.event || v.Name == names.weight {
+			return names, fmt.Errorf("env var %q is also a release context variable", v.Name)
+		}
+	}
+	for _, v := range names.downward {
+		if v.Name == names.event || v.Name == names.weight {
+			return names, fmt.Errorf("env var %q is also a downward API variable", v.Name)
+		}
+	}
+
+	return names, nil
+}
+
+// parseHookEnvVars reads custom env vars from two annotations:
+// 1. Structured: helm.sh/hook-env-vars, a YAML or JSON mapping of hook event to variables
+// 2. Per event: helm.sh/hook-env-<event>, a YAML or JSON mapping of variables
+// Per-event entries override structured ones with the same name. The
+// built-in names are reserved.
+func (p *Processor) parseHookEnvVars(res *Resource, hooks []string, names envNames) (map[string][]envVar, error) {
+	envs := make(map[string][]envVar)
+
+	isHook := make(map[string]bool)
+	for _, h := range hooks {
+		isHook[h] = true
+	}
+
+	structuredKey := p.cfg.annotationKey(annotationHookEnvVars)
+	if value, ok := res.Annotations[structuredKey]; ok {
+		var root yaml.Node
+		if err := yaml.Unmarshal([]byte(value), &root); err != nil {
+			return nil, annotationErrorf(structuredKey, "invalid YAML: %w", err)
+		}
+		mapping := root.Content
+		if len(mapping) == 0 || mapping[0].Kind != yaml.MappingNode {
+			return nil, annotationErrorf(structuredKey, "expected a mapping of hook event to variables")
+		}
+		content := mapping[0].Content
+		for i := 0; i < len(content); i += 2 {
+			hookEvent := content[i].Value
+			if !isHook[hookEvent] {
+				return nil, annotationErrorf(structuredKey, "env vars specified for unknown hook %q", hookEvent)
+			}
+			vars, err := parseEnvVarMapping(content[i+1], names)
+			if err != nil {
+				return nil, annotationErrorf(structuredKey, "hook %q: %w", hookEvent, err)
+			}
+			envs[hookEvent] = mergeEnvVars(envs[hookEvent], vars)
+		}
+	}
+
+	for _, h := range sortedValidHooks() {
+		key := p.eventEnvKey(h)
+		value, ok := res.Annotations[key]
+		if !ok {
+			continue
+		}
+		if !isHook[h] {
+			return nil, annotationErrorf(key, "env vars specified for unknown hook %q", h)
+		}
+		var root yaml.Node
+		if err := yaml.Unmarshal([]byte(value), &root); err != nil {
+			return nil, annotationErrorf(key, "invalid YAML: %w", err)
+		}
+		if len(root.Content) == 0 {
+			return nil, annotationErrorf(key, "expected a mapping of variable names to values")
+		}
+		vars, err := parseEnvVarMapping(root.Content[0], names)
+		if err != nil {
+			return nil, &annotationError{annotation: key, err: err}
+		}
+		envs[h] = mergeEnvVars(envs[h], vars)
+	}
+
+	return envs, nil
+}
+
+// parseEnvVarMapping converts a mapping of variable names to scalar values.
+// The built-in names cannot be used.
+func parseEnvVarMapping(node *yaml.Node, names envNames) ([]envVar, error) {
+	if node.Kind != yaml.MappingNode {
+		return nil, fmt.Errorf("expected a mapping of variable names to values")
+	}
+
+	var vars []envVar
+	seen := make(map[string]bool)
+	for i := 0; i < len(node.Content); i += 2 {
+		name, value := node.Content[i].Value, node.Content[i+1]
+		if !envVarNamePattern.MatchString(name) {
+			return nil, fmt.Errorf("invalid env var name %q", name)
+		}
+		if names.reserved(name) {
+			return nil, fmt.Errorf("env var %q is set by helm-hooks", name)
+		}
+		if value.Kind != yaml.ScalarNode {
+			return nil, fmt.Errorf("env var %q must have a scalar value", name)
+		}
+		if seen[name] {
+			return nil, fmt.Errorf("duplicate env var %q", name)
+		}
+		seen[name] = true
+		vars = append(vars, envVar{Name: name, Value: value.Value})
+	}
+	return vars, nil
+}
+
+// annotationError is an error caused by the value of a specific annotation.
+type annotationError struct {
+	annotation string
+	err        error
+}
+
+// annotationErrorf returns an annotationError for key with a formatted message.
+func annotationErrorf(key, format string, args ...interface{}) error {
+	return &annotationError{annotation: key, err: fmt.Errorf(format, args...)}
+}
+
+func (e *annotationError) Error() string {
+	return e.annotation + ": " + e.err.Error()
+}
+
+func (e *annotationError) Unwrap() error {
+	return e.err
+}
+
+// mergeEnvVars appends vars to base, replacing entries with the same name in place.
+func mergeEnvVars(base, vars []envVar) []envVar {
+	for _, v := range vars {
+		replaced := false
+		for i := range base {
+			if base[i].Name == v.Name {
+				base[i].Value = v.Value
+				replaced = true
+				break
+			}
+		}
+		if !replaced {
+			base = append(base, v)
+		}
+	}
+	return base
+}
+
+// hasCustomEnv reports whether any hook of a resource has custom env vars.
+func hasCustomEnv(specs []hookSpec) bool {
+	for _, spec := range specs {
+		if len(spec.env) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// removeEnvVarAnnotations removes the processed custom env annotations.
+func (p *Processor) removeEnvVarAnnotations(node *yaml.Node) {
+	for _, key := range p.envVarAnnotations() {
+		removeAnnotation(node, key)
+	}
+}
+
+// containerFilter selects the containers to inject into by name, with
+// path.Match glob patterns. A filter is shared by the clones of a split
+// resource, so matches are collected across all of them.
+type containerFilter struct {
+	// include, if set, selects only the matching containers.
+	include []string
+	// exclude skips the matching containers.
+	exclude []string
+	// matched records the patterns that matched a container.
+	matched map[string]bool
+}
+
+// containerPatterns parses the comma-separated glob patterns of an
+// annotation, or returns nil if it is absent.
+func containerPatterns(res *Resource, key string) ([]string, error) {
+	value, ok := res.Annotations[key]
+	if !ok {
+		return nil, nil
+	}
+	patterns := []string{}
+	for _, pattern := range strings.Split(value, ",") {
+		if pattern = strings.TrimSpace(pattern); pattern == "" {
+			continue
+		}
+		if _, err := path.Match(pattern, ""); err != nil {
+			return nil, annotationErrorf(key, "invalid container pattern %q", pattern)
+		}
+		patterns = append(patterns, pattern)
+	}
+	return patterns, nil
+}
+
+// selects reports whether the container named name is injected into. A
+// nil filter selects every container.
+func (f *containerFilter) selects(name string) bool {
+	if f == nil {
+		return true
+	}
+	selected := f.include == nil
+	for _, pattern := range f.include {
+		if ok, _ := path.Match(pattern, name); ok {
+			f.matched[pattern] = true
+			selected = true
+		}
+	}
+	for _, pattern := range f.exclude {
+		if ok, _ := path.Match(pattern, name); ok {
+			f.matched[pattern] = true
+			selected = false
+		}
+	}
+	return selected
+}
+
+// warnUnmatchedContainers warns about container patterns of a resource
+// that matched no container.
+func (p *Processor) warnUnmatchedContainers(res *Resource, names envNames) {
+	f := names.filter
+	if f == nil {
+		return
+	}
+	for _, list := range []struct {
+		key      string
+		patterns []string
+	}{
+		{p.cfg.annotationKey(annotationHookEnvContainers), f.include},
+		{p.cfg.annotationKey(annotationHookEnvExcludeContainers), f.exclude},
+	} {
+		for _, pattern := range list.patterns {
+			if !f.matched[pattern] {
+				p.warn(res, "%s: %q matches no container", list.key, pattern)
+			}
+		}
+	}
+}
@@ -0,0 +1,702 @@
+package hook
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestProcess_StructuredEnvVars(t *testing.T) {
+	input := `apiVersion: batch/v1
+kind: Job
+metadata:
+  name: myapp-migration
+  annotations:
+    helm.sh/hook: pre-install,post-upgrade
+    helm.sh/hook-env-vars: |
+      pre-install:
+        MIGRATION_MODE: full
+        BATCH_SIZE: 1000
+      post-upgrade:
+        MIGRATION_MODE: incremental
+spec:
+  template:
+    spec:
+      containers:
+        - name: migrate
+          image: busybox
+`
+
+	output, err := Process([]byte(input))
+	if err != nil {
+		t.Fatalf("Process failed: %v", err)
+	}
+
+	docs := strings.Split(string(output), "---\n")
+	if len(docs) != 2 {
+		t.Fatalf("Expected 2 documents, got %d", len(docs))
+	}
+
+	if !strings.Contains(docs[0], `value: "full"`) {
+		t.Errorf("Expected MIGRATION_MODE=full on pre-install clone, got:\n%s", docs[0])
+	}
+	if !strings.Contains(docs[0], `value: "1000"`) {
+		t.Errorf("Expected BATCH_SIZE as a string on pre-install clone, got:\n%s", docs[0])
+	}
+	if !strings.Contains(docs[1], `value: "incremental"`) {
+		t.Errorf("Expected MIGRATION_MODE=incremental on post-upgrade clone, got:\n%s", docs[1])
+	}
+	if strings.Contains(docs[1], "BATCH_SIZE") {
+		t.Errorf("BATCH_SIZE should only be set on pre-install, got:\n%s", docs[1])
+	}
+	if strings.Contains(string(output), "hook-env-vars") {
+		t.Error("hook-env-vars should be removed after processing")
+	}
+}
+
+func TestProcess_PerEventEnvVars(t *testing.T) {
+	input := `apiVersion: batch/v1
+kind: Job
+metadata:
+  name: myapp-migration
+  annotations:
+    helm.sh/hook: pre-install,post-upgrade
+    helm.sh/hook-env-vars: '{"pre-install": {"MIGRATION_MODE": "full", "DRY_RUN": "false"}}'
+    helm.sh/hook-env-pre-install: '{"MIGRATION_MODE": "schema-only"}'
+    helm.sh/hook-env-post-upgrade: "MIGRATION_MODE: incremental"
+spec:
+  template:
+    spec:
+      containers:
+        - name: migrate
+          image: busybox
+          env:
+            - name: MIGRATION_MODE
+              value: default
+`
+
+	output, err := Process([]byte(input))
+	if err != nil {
+		t.Fatalf("Process failed: %v", err)
+	}
+
+	docs := strings.Split(string(output), "---\n")
+	if len(docs) != 2 {
+		t.Fatalf("Expected 2 documents, got %d", len(docs))
+	}
+
+	// The per-event annotation overrides the structured one, and the
+	// existing variable is updated in place rather than duplicated
+	if !strings.Contains(docs[0], `value: "schema-only"`) {
+		t.Errorf("Expected per-event value to win on pre-install clone, got:\n%s", docs[0])
+	}
+	if !strings.Contains(docs[0], "DRY_RUN") {
+		t.Errorf("Expected structured DRY_RUN on pre-install clone, got:\n%s", docs[0])
+	}
+	if strings.Count(docs[0], "name: MIGRATION_MODE") != 1 {
+		t.Errorf("Expected MIGRATION_MODE to be updated in place, got:\n%s", docs[0])
+	}
+	if !strings.Contains(docs[1], `value: "incremental"`) {
+		t.Errorf("Expected MIGRATION_MODE=incremental on post-upgrade clone, got:\n%s", docs[1])
+	}
+	if strings.Contains(string(output), "hook-env-pre-install") || strings.Contains(string(output), "hook-env-post-upgrade") {
+		t.Error("per-event env annotations should be removed after processing")
+	}
+}
+
+func TestProcess_EnvVarsSingleHook(t *testing.T) {
+	input := `apiVersion: batch/v1
+kind: Job
+metadata:
+  name: myapp-init
+  annotations:
+    helm.sh/hook: pre-install
+    helm.sh/hook-env-pre-install: "MODE: full"
+spec:
+  template:
+    spec:
+      containers:
+        - name: init
+          image: busybox
+`
+
+	output, err := Process([]byte(input))
+	if err != nil {
+		t.Fatalf("Process failed: %v", err)
+	}
+
+	if !strings.Contains(string(output), "name: MODE") {
+		t.Errorf("Expected MODE on single hook, got:\n%s", output)
+	}
+	if strings.Contains(string(output), "hook-env-pre-install") {
+		t.Error("hook-env-pre-install should be removed after processing")
+	}
+}
+
+func TestProcess_EnvVarsDisabled(t *testing.T) {
+	input := `apiVersion: batch/v1
+kind: Job
+metadata:
+  name: myapp-init
+  annotations:
+    helm.sh/hook: pre-install
+    helm.sh/hook-env: "false"
+    helm.sh/hook-env-pre-install: "MODE: full"
+spec:
+  template:
+    spec:
+      containers:
+        - name: init
+          image: busybox
+`
+
+	var warnings []Warning
+	cfg := DefaultConfig()
+	cfg.OnWarning = func(w Warning) { warnings = append(warnings, w) }
+
+	output, err := NewProcessor(cfg).Process([]byte(input))
+	if err != nil {
+		t.Fatalf("Process failed: %v", err)
+	}
+
+	if strings.Contains(string(output), "MODE") {
+		t.Errorf("Expected no env vars when hook-env is false, got:\n%s", output)
+	}
+	if len(warnings) != 1 || !strings.Contains(warnings[0].Message, "ignored") {
+		t.Errorf("Expected one warning about ignored env vars, got %v", warnings)
+	}
+}
+
+func TestProcess_InvalidEnvVars(t *testing.T) {
+	tests := []struct {
+		name       string
+		annotation string
+		want       string
+	}{
+		{"unknown hook", `helm.sh/hook-env-vars: '{"post-delete": {"A": "b"}}'`, "unknown hook"},
+		{"unknown per-event hook", `helm.sh/hook-env-post-delete: "A: b"`, "unknown hook"},
+		{"builtin name", `helm.sh/hook-env-pre-install: "HELM_HOOK_EVENT: x"`, "set by helm-hooks"},
+		{"invalid name", `helm.sh/hook-env-pre-install: "1BAD: x"`, "invalid env var name"},
+		{"non-scalar value", `helm.sh/hook-env-pre-install: "A: [1, 2]"`, "scalar"},
+		{"not a mapping", `helm.sh/hook-env-vars: "pre-install"`, "expected a mapping"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			input := `apiVersion: batch/v1
+kind: Job
+metadata:
+  name: myapp-init
+  annotations:
+    helm.sh/hook: pre-install
+    ` + tt.annotation + `
+spec:
+  template:
+    spec:
+      containers:
+        - name: init
+          image: busybox
+`
+
+			_, err := Process([]byte(input))
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Expected error containing %q, got: %v", tt.want, err)
+			}
+		})
+	}
+}
+
+func TestLint_EnvVarsAnnotation(t *testing.T) {
+	input := `apiVersion: batch/v1
+kind: Job
+metadata:
+  name: myapp-init
+  annotations:
+    helm.sh/hook: pre-install
+    helm.sh/hook-env-pre-install: "HELM_HOOK_WEIGHT: 1"
+`
+
+	problems := Lint([]byte(input))
+	if len(problems) != 1 {
+		t.Fatalf("Expected 1 problem, got %v", problems)
+	}
+	if problems[0].Annotation != "helm.sh/hook-env-pre-install" {
+		t.Errorf("Expected problem on helm.sh/hook-env-pre-install, got %q", problems[0].Annotation)
+	}
+}
+
+func TestProcess_EnvNamesAnnotation(t *testing.T) {
+	input := `apiVersion: batch/v1
+kind: Job
+metadata:
+  name: legacy
+  annotations:
+    helm.sh/hook: pre-install
+    helm.sh/hook-env-names: "event=HOOK_PHASE"
+spec:
+  template:
+    spec:
+      containers:
+        - name: main
+          image: busybox
+          env:
+            - name: HOOK_PHASE
+              value: unset
+`
+
+	output, err := Process([]byte(input))
+	if err != nil {
+		t.Fatalf("Process failed: %v", err)
+	}
+
+	result := string(output)
+	// The existing entry is updated in place, not duplicated
+	if strings.Count(result, "name: HOOK_PHASE") != 1 || !strings.Contains(result, "name: HOOK_PHASE\n              value: \"pre-install\"") {
+		t.Errorf("Expected HOOK_PHASE updated in place, got:\n%s", result)
+	}
+	if strings.Contains(result, "HELM_HOOK_EVENT") {
+		t.Errorf("HELM_HOOK_EVENT should be renamed, got:\n%s", result)
+	}
+	if !strings.Contains(result, "name: HELM_HOOK_WEIGHT") {
+		t.Errorf("Expected the weight variable to keep its default name, got:\n%s", result)
+	}
+	if strings.Contains(result, "hook-env-names") {
+		t.Error("hook-env-names should be removed after processing")
+	}
+}
+
+func TestProcess_EnvPrefix(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.EnvVarPrefix = "APP_"
+	input := dependsJob("a", `    helm.sh/hook: pre-install
+`) + "---\n" + dependsJob("b", `    helm.sh/hook: pre-install,post-install
+    helm.sh/hook-env-prefix: "TEAM_"
+    helm.sh/hook-env-names: "weight=ORDER"
+`)
+
+	output, err := NewProcessor(cfg).Process([]byte(input))
+	if err != nil {
+		t.Fatalf("Process failed: %v", err)
+	}
+
+	docs := strings.Split(string(output), "---\n")
+	if len(docs) != 3 {
+		t.Fatalf("Expected 3 documents, got %d", len(docs))
+	}
+	if !strings.Contains(docs[0], "name: APP_HELM_HOOK_EVENT") || !strings.Contains(docs[0], "name: APP_HELM_HOOK_WEIGHT") {
+		t.Errorf("Expected the global prefix, got:\n%s", docs[0])
+	}
+	if !strings.Contains(docs[1], "name: TEAM_HELM_HOOK_EVENT") || !strings.Contains(docs[1], "name: TEAM_ORDER") {
+		t.Errorf("Expected the annotation prefix and names, got:\n%s", docs[1])
+	}
+}
+
+func TestProcess_InvalidEnvNames(t *testing.T) {
+	tests := []struct {
+		name        string
+		annotations string
+		want        string
+	}{
+		{name: "unknown variable", annotations: `    helm.sh/hook-env-names: "phase=X"
+`, want: `unknown built-in env var "phase"`},
+		{name: "invalid name", annotations: `    helm.sh/hook-env-names: "event=1X"
+`, want: `invalid env var name "1X"`},
+		{name: "same name", annotations: `    helm.sh/hook-env-names: "event=X,weight=X"
+`, want: `same name "X"`},
+		{name: "invalid prefix", annotations: `    helm.sh/hook-env-prefix: "9_"
+`, want: `invalid env var prefix "9_"`},
+		{name: "custom var uses renamed built-in", annotations: `    helm.sh/hook-env-names: "event=PHASE"
+    helm.sh/hook-env-pre-install: "PHASE: x"
+`, want: `env var "PHASE" is set by helm-hooks`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			input := dependsJob("job", "    helm.sh/hook: pre-install\n"+tt.annotations)
+			_, err := Process([]byte(input))
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Expected error containing %q, got: %v", tt.want, err)
+			}
+		})
+	}
+
+	problems := Lint([]byte(dependsJob("job", `    helm.sh/hook: pre-install
+    helm.sh/hook-env-names: "event=1X"
+`)))
+	if len(problems) != 1 || problems[0].Annotation != "helm.sh/hook-env-names" {
+		t.Errorf("Expected one problem on helm.sh/hook-env-names, got %v", problems)
+	}
+}
+
+func TestProcess_ReleaseEnv(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Release = Release{Name: "shop", Namespace: "prod", Revision: "4", ChartVersion: "1.2.0"}
+	input := dependsJob("job", `    helm.sh/hook: pre-install
+`)
+
+	output, err := NewProcessor(cfg).Process([]byte(input))
+	if err != nil {
+		t.Fatalf("Process failed: %v", err)
+	}
+
+	want := `            - name: HELM_HOOK_WEIGHT
+              value: "0"
+            - name: HELM_RELEASE_NAME
+              value: "shop"
+            - name: HELM_RELEASE_NAMESPACE
+              value: "prod"
+            - name: HELM_RELEASE_REVISION
+              value: "4"
+            - name: HELM_CHART_VERSION
+              value: "1.2.0"
+`
+	if !strings.Contains(string(output), want) {
+		t.Errorf("Expected release env vars after the hook vars, got:\n%s", output)
+	}
+	// Empty fields are not injected
+	if strings.Contains(string(output), "HELM_CHART_NAME") {
+		t.Errorf("Expected no HELM_CHART_NAME, got:\n%s", output)
+	}
+}
+
+func TestProcess_ReleaseEnvReserved(t *testing.T) {
+	input := dependsJob("job", `    helm.sh/hook: pre-install
+    helm.sh/hook-env-pre-install: "HELM_RELEASE_NAME: other"
+`)
+
+	cfg := DefaultConfig()
+	cfg.Release = Release{Name: "shop"}
+	_, err := NewProcessor(cfg).Process([]byte(input))
+	if err == nil || !strings.Contains(err.Error(), `env var "HELM_RELEASE_NAME" is set by helm-hooks`) {
+		t.Errorf("Expected reserved var error, got: %v", err)
+	}
+
+	// Without a release name the variable is free for custom use
+	output, err := Process([]byte(input))
+	if err != nil {
+		t.Fatalf("Process failed: %v", err)
+	}
+	if !strings.Contains(string(output), `value: "other"`) {
+		t.Errorf("Expected the custom value, got:\n%s", output)
+	}
+
+	_, err = NewProcessor(cfg).Process([]byte(dependsJob("job", `    helm.sh/hook: pre-install
+    helm.sh/hook-env-names: "event=HELM_RELEASE_NAME"
+`)))
+	if err == nil || !strings.Contains(err.Error(), "release context variable") {
+		t.Errorf("Expected clash with release var, got: %v", err)
+	}
+}
+
+func TestProcess_DownwardEnv(t *testing.T) {
+	input := `apiVersion: batch/v1
+kind: Job
+metadata:
+  name: migrate
+  annotations:
+    helm.sh/hook: pre-install
+    helm.sh/hook-weight: "-5"
+    helm.sh/hook-env-downward: pod,hook
+spec:
+  template:
+    spec:
+      containers:
+        - name: main
+          env:
+            - name: POD_NAME
+              value: fixed
+`
+
+	output, err := Process([]byte(input))
+	if err != nil {
+		t.Fatalf("Process failed: %v", err)
+	}
+
+	want := `spec:
+  template:
+    metadata:
+      annotations:
+        helm.sh/hook: "pre-install"
+        helm.sh/hook-weight: "-5"
+    spec:
+      containers:
+        - name: main
+          env:
+            - name: POD_NAME
+              value: fixed
+            - name: HELM_HOOK_EVENT
+              valueFrom:
+                fieldRef:
+                  fieldPath: metadata.annotations['helm.sh/hook']
+            - name: HELM_HOOK_WEIGHT
+              valueFrom:
+                fieldRef:
+                  fieldPath: metadata.annotations['helm.sh/hook-weight']
+            - name: POD_NAMESPACE
+              valueFrom:
+                fieldRef:
+                  fieldPath: metadata.namespace
+            - name: NODE_NAME
+              valueFrom:
+                fieldRef:
+                  fieldPath: spec.nodeName
+`
+	if !strings.HasSuffix(string(output), want) {
+		t.Errorf("Unexpected output:\n%s", output)
+	}
+	if strings.Contains(string(output), "hook-env-downward") {
+		t.Errorf("Expected helm.sh/hook-env-downward to be removed:\n%s", output)
+	}
+}
+
+func TestProcess_EnvKeepsValueFrom(t *testing.T) {
+	input := `apiVersion: v1
+kind: Pod
+metadata:
+  name: seed
+  annotations:
+    helm.sh/hook: post-install
+spec:
+  containers:
+    - name: main
+      env:
+        - name: HELM_HOOK_EVENT
+          valueFrom:
+            configMapKeyRef: {name: hooks, key: event}
+        - name: HELM_HOOK_WEIGHT
+          value: stale
+`
+
+	cfg := DefaultConfig()
+	cfg.DownwardEnv = []string{"hook"}
+	output, err := NewProcessor(cfg).Process([]byte(input))
+	if err != nil {
+		t.Fatalf("Process failed: %v", err)
+	}
+
+	// User-defined valueFrom entries are kept, literals are replaced
+	want := `      env:
+        - name: HELM_HOOK_EVENT
+          valueFrom:
+            configMapKeyRef: {name: hooks, key: event}
+        - name: HELM_HOOK_WEIGHT
+          valueFrom:
+            fieldRef:
+              fieldPath: metadata.annotations['helm.sh/hook-weight']
+`
+	if !strings.HasSuffix(string(output), want) {
+		t.Errorf("Unexpected output:\n%s", output)
+	}
+	// A Pod's own annotations are read
+	if !strings.Contains(string(output), `helm.sh/hook-weight: "0"`) {
+		t.Errorf("Expected the weight annotation on the Pod:\n%s", output)
+	}
+}
+
+func TestProcess_InvalidDownwardEnv(t *testing.T) {
+	_, err := Process([]byte(dependsJob("job", `    helm.sh/hook: pre-install
+    helm.sh/hook-env-downward: "node"
+`)))
+	if err == nil || !strings.Contains(err.Error(), `helm.sh/hook-env-downward: unknown downward env source "node"`) {
+		t.Errorf("Expected unknown source error, got: %v", err)
+	}
+
+	_, err = Process([]byte(dependsJob("job", `    helm.sh/hook: pre-install
+    helm.sh/hook-env-downward: "pod"
+    helm.sh/hook-env-pre-install: "NODE_NAME: x"
+`)))
+	if err == nil || !strings.Contains(err.Error(), `env var "NODE_NAME" is set by helm-hooks`) {
+		t.Errorf("Expected reserved var error, got: %v", err)
+	}
+
+	cfg := DefaultConfig()
+	cfg.Profile = ProfileArgoCD
+	cfg.DownwardEnv = []string{"hook"}
+	_, err = NewProcessor(cfg).Process([]byte(`apiVersion: v1
+kind: Pod
+metadata:
+  name: seed
+  annotations:
+    helm.sh/hook: post-install
+spec:
+  containers:
+    - name: main
+`))
+	if err == nil || !strings.Contains(err.Error(), "needs the helm profile") {
+		t.Errorf("Expected profile error, got: %v", err)
+	}
+}
+
+func TestProcess_EnvFromMode(t *testing.T) {
+	input := dependsJob("migrate", `    helm.sh/hook: pre-install,post-upgrade
+    helm.sh/hook-weights: "-5,10"
+    helm.sh/hook-delete-policy: hook-succeeded
+    helm.sh/hook-env-mode: envFrom
+`)
+
+	output, report, err := ProcessWithReport([]byte(input))
+	if err != nil {
+		t.Fatalf("Process failed: %v", err)
+	}
+
+	docs := strings.Split(string(output), "---\n")
+	if len(docs) != 4 {
+		t.Fatalf("Expected a ConfigMap before each split hook, got %d documents:\n%s", len(docs), output)
+	}
+	wantConfigMap := `apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: migrate-pre-install-env
+  annotations:
+    helm.sh/hook: "pre-install"
+    helm.sh/hook-weight: "-6"
+    helm.sh/hook-delete-policy: "hook-succeeded"
+data:
+  HELM_HOOK_EVENT: "pre-install"
+  HELM_HOOK_WEIGHT: "-5"
+`
+	if docs[0] != wantConfigMap {
+		t.Errorf("ConfigMap =\n%s\nwant\n%s", docs[0], wantConfigMap)
+	}
+	if !strings.Contains(docs[1], "envFrom:\n            - configMapRef:\n                name: migrate-pre-install-env\n") {
+		t.Errorf("Expected an envFrom reference, got:\n%s", docs[1])
+	}
+	if strings.Contains(docs[1], "HELM_HOOK_EVENT") {
+		t.Errorf("Expected no env entries, got:\n%s", docs[1])
+	}
+	if !strings.Contains(docs[2], "name: migrate-post-upgrade-env") || !strings.Contains(docs[2], `helm.sh/hook-weight: "9"`) {
+		t.Errorf("Unexpected post-upgrade ConfigMap:\n%s", docs[2])
+	}
+
+	outputs := report.Documents[0].Outputs
+	if len(outputs) != 4 || !outputs[0].Generated || outputs[0].Weight != -6 || outputs[1].Generated {
+		t.Errorf("Unexpected report outputs: %+v", outputs)
+	}
+}
+
+func TestProcess_EphemeralContainers(t *testing.T) {
+	input := `apiVersion: v1
+kind: Pod
+metadata:
+  name: seed
+  annotations:
+    helm.sh/hook: post-install
+spec:
+  containers:
+    - name: main
+  ephemeralContainers:
+    - name: debug
+`
+
+	output, err := Process([]byte(input))
+	if err != nil {
+		t.Fatalf("Process failed: %v", err)
+	}
+	if got := strings.Count(string(output), "name: HELM_HOOK_EVENT"); got != 1 {
+		t.Errorf("Expected ephemeral containers to be skipped by default, got:\n%s", output)
+	}
+
+	cfg := DefaultConfig()
+	cfg.EnvEphemeralContainers = true
+	output, err = NewProcessor(cfg).Process([]byte(input))
+	if err != nil {
+		t.Fatalf("Process failed: %v", err)
+	}
+	if got := strings.Count(string(output), "name: HELM_HOOK_EVENT"); got != 2 {
+		t.Errorf("Expected ephemeral containers to be injected, got:\n%s", output)
+	}
+}
+
+func TestProcess_EnvContainers(t *testing.T) {
+	input := `apiVersion: batch/v1
+kind: Job
+metadata:
+  name: migrate
+  annotations:
+    helm.sh/hook: pre-install,post-install
+    helm.sh/hook-env-containers: "migrate,seed-*,worker"
+    helm.sh/hook-env-exclude-containers: "*-proxy,seed-skip"
+spec:
+  template:
+    spec:
+      initContainers:
+        - name: seed-data
+      containers:
+        - name: migrate
+        - name: seed-skip
+        - name: cloud-sql-proxy
+        - name: other
+`
+
+	cfg := DefaultConfig()
+	var warnings []Warning
+	cfg.OnWarning = func(w Warning) { warnings = append(warnings, w) }
+	_, report, err := NewProcessor(cfg).ProcessWithReport([]byte(input))
+	if err != nil {
+		t.Fatalf("Process failed: %v", err)
+	}
+
+	for _, out := range report.Documents[0].Outputs {
+		if got := strings.Join(out.EnvContainers, ","); got != "seed-data,migrate" {
+			t.Errorf("%s: env containers = %q, want seed-data,migrate", out.Name, got)
+		}
+	}
+	// Reported once for the resource, not per split clone
+	if len(warnings) != 1 || !strings.Contains(warnings[0].Message, `helm.sh/hook-env-containers: "worker" matches no container`) {
+		t.Errorf("Expected one warning about worker, got %v", warnings)
+	}
+}
+
+func TestProcess_InvalidEnvContainers(t *testing.T) {
+	_, err := Process([]byte(dependsJob("job", `    helm.sh/hook: pre-install
+    helm.sh/hook-env-exclude-containers: "sidecar-["
+`)))
+	if err == nil || !strings.Contains(err.Error(), `helm.sh/hook-env-exclude-containers: invalid container pattern "sidecar-["`) {
+		t.Errorf("Expected invalid pattern error, got: %v", err)
+	}
+}
+
+func TestProcess_EnvFromModeNoContainers(t *testing.T) {
+	input := dependsJob("migrate", `    helm.sh/hook: pre-install
+    helm.sh/hook-env-mode: envFrom
+    helm.sh/hook-env-containers: "worker"
+`)
+
+	output, report, err := ProcessWithReport([]byte(input))
+	if err != nil {
+		t.Fatalf("Process failed: %v", err)
+	}
+	if strings.Contains(string(output), "kind: ConfigMap") || strings.Contains(string(output), "envFrom") {
+		t.Errorf("Expected no ConfigMap when no container is selected, got:\n%s", output)
+	}
+	if outputs := report.Documents[0].Outputs; len(outputs) != 1 || outputs[0].Generated {
+		t.Errorf("Unexpected outputs: %+v", outputs)
+	}
+}
+
+func TestProcess_EnvFromModeUnnamedContainers(t *testing.T) {
+	input := `apiVersion: argoproj.io/v1alpha1
+kind: Workflow
+metadata:
+  name: migrate
+  annotations:
+    helm.sh/hook: pre-install
+    helm.sh/hook-env-mode: envFrom
+spec:
+  entrypoint: main
+  templates:
+    - name: main
+      container:
+        image: busybox
+`
+
+	output, report, err := ProcessWithReport([]byte(input))
+	if err != nil {
+		t.Fatalf("Process failed: %v", err)
+	}
+	if !strings.Contains(string(output), "kind: ConfigMap") || strings.Count(string(output), "name: migrate-env") != 2 {
+		t.Errorf("Expected a ConfigMap referenced by the unnamed container, got:\n%s", output)
+	}
+	if outputs := report.Documents[0].Outputs; len(outputs) != 2 || !outputs[0].Generated {
+		t.Errorf("Unexpected outputs: %+v", outputs)
+	}
+}
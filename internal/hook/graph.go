@@ -0,0 +1,151 @@
+package hook
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// graphNode is a node in the rendered hook timeline.
+type graphNode struct {
+	id    string
+	label []string
+}
+
+// graphLane groups the nodes of one plan phase.
+type graphLane struct {
+	phase PlanPhase
+	nodes []graphNode
+}
+
+// graphLanes converts the plan into lanes of nodes. Hook phases become
+// one node per hook (or a placeholder when empty); release phases
+// become a single node.
+func (p *ExecutionPlan) graphLanes() []graphLane {
+	var lanes []graphLane
+
+	for _, phase := range p.Phases {
+		id := graphID(phase.Name)
+		lane := graphLane{phase: phase}
+
+		switch {
+		case phase.IsRelease():
+			lane.nodes = []graphNode{{
+				id:    id,
+				label: []string{phase.Name, fmt.Sprintf("%d release manifests", phase.Manifests)},
+			}}
+		case len(phase.Hooks) == 0:
+			lane.nodes = []graphNode{{id: id + "_none", label: []string{"no hooks"}}}
+		default:
+			for i, h := range phase.Hooks {
+				lane.nodes = append(lane.nodes, graphNode{
+					id:    fmt.Sprintf("%s_%d", id, i),
+					label: []string{h.Kind + "/" + h.Name, fmt.Sprintf("weight %d", h.Weight)},
+				})
+			}
+		}
+
+		lanes = append(lanes, lane)
+	}
+
+	return lanes
+}
+
+// graphEdges returns the execution order as consecutive node pairs.
+func graphEdges(lanes []graphLane) [][2]string {
+	var edges [][2]string
+	prev := ""
+	for _, lane := range lanes {
+		for _, n := range lane.nodes {
+			if prev != "" {
+				edges = append(edges, [2]string{prev, n.id})
+			}
+			prev = n.id
+		}
+	}
+	return edges
+}
+
+// WriteMermaid writes the plan as a Mermaid flowchart with one lane per phase.
+func (p *ExecutionPlan) WriteMermaid(w io.Writer) error {
+	var buf bytes.Buffer
+	lanes := p.graphLanes()
+
+	fmt.Fprintln(&buf, "graph LR")
+	for _, lane := range lanes {
+		if lane.phase.IsRelease() {
+			n := lane.nodes[0]
+			fmt.Fprintf(&buf, "    %s([\"%s\"])\n", n.id, mermaidLabel(n.label))
+			continue
+		}
+
+		fmt.Fprintf(&buf, "    subgraph %s_lane[\"%s\"]\n", graphID(lane.phase.Name), lane.phase.Name)
+		fmt.Fprintln(&buf, "        direction TB")
+		for _, n := range lane.nodes {
+			fmt.Fprintf(&buf, "        %s[\"%s\"]\n", n.id, mermaidLabel(n.label))
+		}
+		fmt.Fprintln(&buf, "    end")
+	}
+	for _, e := range graphEdges(lanes) {
+		fmt.Fprintf(&buf, "    %s --> %s\n", e[0], e[1])
+	}
+
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+// WriteDOT writes the plan as a Graphviz digraph with one cluster per phase.
+func (p *ExecutionPlan) WriteDOT(w io.Writer) error {
+	var buf bytes.Buffer
+	lanes := p.graphLanes()
+
+	fmt.Fprintf(&buf, "digraph %q {\n", "helm-hooks-"+p.Operation)
+	fmt.Fprintln(&buf, "    rankdir=LR;")
+	fmt.Fprintln(&buf, "    node [shape=box];")
+	for i, lane := range lanes {
+		if lane.phase.IsRelease() {
+			n := lane.nodes[0]
+			fmt.Fprintf(&buf, "    %s [label=%s, shape=ellipse];\n", n.id, dotLabel(n.label))
+			continue
+		}
+
+		fmt.Fprintf(&buf, "    subgraph cluster_%d {\n", i)
+		fmt.Fprintf(&buf, "        label=%q;\n", lane.phase.Name)
+		for _, n := range lane.nodes {
+			fmt.Fprintf(&buf, "        %s [label=%s];\n", n.id, dotLabel(n.label))
+		}
+		fmt.Fprintln(&buf, "    }")
+	}
+	for _, e := range graphEdges(lanes) {
+		fmt.Fprintf(&buf, "    %s -> %s;\n", e[0], e[1])
+	}
+	fmt.Fprintln(&buf, "}")
+
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+// graphID converts a phase name into an identifier valid in Mermaid and DOT.
+func graphID(name string) string {
+	return strings.Map(func(r rune) rune {
+		if (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') {
+			return r
+		}
+		return '_'
+	}, name)
+}
+
+// mermaidLabel joins label lines for a quoted Mermaid node label.
+func mermaidLabel(lines []string) string {
+	return strings.ReplaceAll(strings.Join(lines, "<br/>"), `"`, "#quot;")
+}
+
+// dotLabel joins label lines into a quoted DOT string.
+func dotLabel(lines []string) string {
+	escaped := make([]string, len(lines))
+	for i, l := range lines {
+		escaped[i] = strings.Trim(fmt.Sprintf("%q", l), `"`)
+	}
+	return `"` + strings.Join(escaped, `\n`) + `"`
+}
@@ -0,0 +1,59 @@
+package hook
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestExecutionPlan_WriteMermaid(t *testing.T) {
+	plan, err := Plan([]byte(planInput), "upgrade")
+	if err != nil {
+		t.Fatalf("Plan failed: %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := plan.WriteMermaid(&buf); err != nil {
+		t.Fatalf("WriteMermaid failed: %v", err)
+	}
+
+	out := buf.String()
+	for _, want := range []string{
+		"graph LR",
+		`subgraph pre_upgrade_lane["pre-upgrade"]`,
+		`pre_upgrade_0["Job/myapp-migration-pre-upgrade<br/>weight -50"]`,
+		`apply(["apply<br/>2 release manifests"])`,
+		"pre_upgrade_1 --> apply",
+		"apply --> post_upgrade_0",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("Expected %q in mermaid output:\n%s", want, out)
+		}
+	}
+}
+
+func TestExecutionPlan_WriteDOT(t *testing.T) {
+	plan, err := Plan([]byte(planInput), "install")
+	if err != nil {
+		t.Fatalf("Plan failed: %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := plan.WriteDOT(&buf); err != nil {
+		t.Fatalf("WriteDOT failed: %v", err)
+	}
+
+	out := buf.String()
+	for _, want := range []string{
+		`digraph "helm-hooks-install" {`,
+		`label="pre-install";`,
+		`apply [label="apply\n2 release manifests", shape=ellipse];`,
+		`post_install_none [label="no hooks"];`,
+		"pre_install_0 -> apply;",
+		"apply -> post_install_none;",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("Expected %q in DOT output:\n%s", want, out)
+		}
+	}
+}
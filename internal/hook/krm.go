@@ -0,0 +1,400 @@
+package hook
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// resourceListKind is the kind of a KRM function's input and output.
+const resourceListKind = "ResourceList"
+
+// resourceListGroup is the API group of a ResourceList, e.g. in
+// config.kubernetes.io/v1.
+const resourceListGroup = "config.kubernetes.io/"
+
+// krmItemAnnotations identify an item's position in the ResourceList.
+// They are removed from split clones so the orchestrator treats each
+// clone as a new resource instead of a duplicate of the original.
+var krmItemAnnotations = []string{
+	"config.kubernetes.io/index",
+	"internal.config.kubernetes.io/index",
+	"config.k8s.io/id",
+	"internal.config.kubernetes.io/id",
+}
+
+// ErrFunctionFailed is returned by ProcessResourceList when at least one
+// item was rejected. The returned ResourceList is still valid and
+// describes each problem in its results field.
+var ErrFunctionFailed = errors.New("function reported errors")
+
+// krmResult is a single entry in a ResourceList's results field.
+type krmResult struct {
+	Message     string          `yaml:"message"`
+	Severity    string          `yaml:"severity"`
+	ResourceRef *krmResourceRef `yaml:"resourceRef,omitempty"`
+	Field       *krmField       `yaml:"field,omitempty"`
+}
+
+// krmResourceRef identifies the item a result refers to.
+type krmResourceRef struct {
+	APIVersion string `yaml:"apiVersion,omitempty"`
+	Kind       string `yaml:"kind,omitempty"`
+	Name       string `yaml:"name,omitempty"`
+	Namespace  string `yaml:"namespace,omitempty"`
+}
+
+// krmField identifies the field a result refers to.
+type krmField struct {
+	Path string `yaml:"path"`
+}
+
+// IsResourceList reports whether the first document of input is a KRM
+// function ResourceList, i.e. has a config.kubernetes.io apiVersion and
+// kind ResourceList. Only the top-level keys of the first document are
+// read, so input may be the beginning of a larger stream; later
+// documents, such as a rendered manifest containing a ResourceList, are
+// ignored.
+func IsResourceList(input []byte) bool {
+	var apiVersion, kind string
+	started := false
+	for _, line := range strings.Split(string(input), "\n") {
+		line = strings.TrimRight(line, " \r")
+		if line == "---" || strings.HasPrefix(line, "--- ") {
+			if started {
+				break
+			}
+			continue
+		}
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		started = true
+		if key, value, ok := strings.Cut(line, ":"); ok {
+			switch key {
+			case "apiVersion":
+				apiVersion = strings.Trim(strings.TrimSpace(value), `"'`)
+			case "kind":
+				kind = strings.Trim(strings.TrimSpace(value), `"'`)
+			}
+		}
+	}
+	return kind == resourceListKind && strings.HasPrefix(apiVersion, resourceListGroup)
+}
+
+// ProcessResourceList runs helm-hooks as a KRM function using the default
+// configuration, as modified by the ResourceList's functionConfig.
+func ProcessResourceList(input []byte) ([]byte, error) {
+	return defaultProcessor.ProcessResourceList(input)
+}
+
+// ProcessResourceList reads a KRM function ResourceList, applies the same
+// transformation Process does to each item and returns the resulting
+// ResourceList. Multi-hook items are replaced by their split clones.
+//
+// Problems are reported in the results field. If any item is rejected,
+// it is left unchanged and ErrFunctionFailed is returned together with
+// the output.
+func (p *Processor) ProcessResourceList(input []byte) ([]byte, error) {
+	dec := yaml.NewDecoder(bytes.NewReader(input))
+	var doc yaml.Node
+	if err := dec.Decode(&doc); err != nil {
+		return nil, fmt.Errorf("parsing ResourceList: %w", err)
+	}
+	// Documents after the ResourceList would be silently dropped
+	for {
+		var extra yaml.Node
+		err := dec.Decode(&extra)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil || len(extra.Content) > 0 && extra.Content[0].Tag != "!!null" {
+			return nil, fmt.Errorf("input has more than one document; a %s must be the only one", resourceListKind)
+		}
+	}
+
+	root := &doc
+	if doc.Kind == yaml.DocumentNode && len(doc.Content) > 0 {
+		root = doc.Content[0]
+	}
+	if root.Kind != yaml.MappingNode || mappingValue(root, "kind") == nil || mappingValue(root, "kind").Value != resourceListKind {
+		return nil, fmt.Errorf("input is not a %s", resourceListKind)
+	}
+
+	var results []krmResult
+	failed := false
+
+	// Collect warnings as results of the item being processed
+	var warnings []Warning
+	fnProcessor, err := p.withFunctionConfig(mappingValue(root, "functionConfig"), func(w Warning) {
+		warnings = append(warnings, w)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("functionConfig: %w", err)
+	}
+	// The items are one stream, like the documents of ProcessStream
+	fnProcessor = fnProcessor.forStream()
+
+	items := mappingValue(root, "items")
+	if items != nil && items.Kind == yaml.SequenceNode {
+		// Resolve dependencies across all items first. If that fails,
+		// items declaring dependencies are left unchanged.
+		deps := fnProcessor.newDependencyResolver()
+		dependent := make(map[int]bool)
+		for i, item := range items.Content {
+			dependent[i] = deps.add(item, i+1)
+		}
+		depsErr := deps.resolve()
+		if depsErr != nil {
+			result := krmResult{Message: depsErr.Error(), Severity: "error"}
+			var depErr *dependencyError
+			if errors.As(depsErr, &depErr) {
+				result.Message = depErr.err.Error()
+				result.ResourceRef = itemRef(items.Content[depErr.index-1], depErr.res)
+				result.Field = &krmField{Path: "metadata.annotations." + fnProcessor.cfg.annotationKey(annotationHookDependsOn)}
+			}
+			results = append(results, result)
+			failed = true
+		}
+
+		release := fnProcessor.newReleaseValidator()
+		var newItems []*yaml.Node
+		for i, item := range items.Content {
+			if depsErr != nil && dependent[i] {
+				newItems = append(newItems, item)
+				continue
+			}
+			out, itemResults := fnProcessor.processItem(item, i+1)
+			for _, w := range warnings {
+				itemResults = append(itemResults, krmResult{Message: w.Message, Severity: "warning", ResourceRef: itemRef(item, &Resource{Kind: w.Kind, Name: w.Name})})
+			}
+			warnings = nil
+			for _, problem := range release.add(i+1, out) {
+				result := krmResult{Message: problem.Message, Severity: "error", ResourceRef: itemRef(item, &Resource{Kind: problem.Kind, Name: problem.Name})}
+				if problem.Annotation != "" {
+					result.Field = &krmField{Path: "metadata.annotations." + problem.Annotation}
+				}
+				itemResults = append(itemResults, result)
+			}
+			results = append(results, itemResults...)
+			for _, r := range itemResults {
+				if r.Severity == "error" {
+					failed = true
+				}
+			}
+			newItems = append(newItems, out...)
+		}
+		items.Content = newItems
+	}
+
+	// Append our results to any left by earlier functions
+	if len(results) > 0 {
+		resultsNode := &yaml.Node{}
+		if err := resultsNode.Encode(results); err != nil {
+			return nil, err
+		}
+		if existing := mappingValue(root, "results"); existing != nil && existing.Kind == yaml.SequenceNode {
+			existing.Content = append(existing.Content, resultsNode.Content...)
+		} else {
+			setMappingValue(root, "results", resultsNode)
+		}
+	}
+
+	output, err := marshalNode(&doc)
+	if err != nil {
+		return nil, err
+	}
+	if failed {
+		return output, ErrFunctionFailed
+	}
+	return output, nil
+}
+
+// processItem transforms one ResourceList item. It returns the items that
+// replace it and any results to report. Rejected items are kept unchanged.
+func (p *Processor) processItem(item *yaml.Node, index int) ([]*yaml.Node, []krmResult) {
+	res, err := parseResource(item)
+	if err != nil {
+		return []*yaml.Node{item}, []krmResult{{Message: err.Error(), Severity: "error"}}
+	}
+	ref := itemRef(item, res)
+
+	// Lint first so problems carry the annotation they came from
+	if problems := p.lintDocument(item, index); len(problems) > 0 {
+		var results []krmResult
+		for _, problem := range problems {
+			result := krmResult{Message: problem.Message, Severity: "error", ResourceRef: ref}
+			if problem.Annotation != "" {
+				result.Field = &krmField{Path: "metadata.annotations." + problem.Annotation}
+			}
+			results = append(results, result)
+		}
+		return []*yaml.Node{item}, results
+	}
+
+	processed, err := p.processDocument(item)
+	if err != nil {
+		return []*yaml.Node{item}, []krmResult{{Message: err.Error(), Severity: "error", ResourceRef: ref}}
+	}
+	if processed == nil {
+		return []*yaml.Node{item}, nil
+	}
+
+	var out []*yaml.Node
+	for _, node := range processed {
+		content := node
+		if node.Kind == yaml.DocumentNode && len(node.Content) > 0 {
+			content = node.Content[0]
+		}
+		if len(processed) > 1 {
+			for _, key := range krmItemAnnotations {
+				removeAnnotation(content, key)
+			}
+		}
+		out = append(out, content)
+	}
+	return out, nil
+}
+
+// withFunctionConfig returns a Processor whose configuration is overridden
+// by the data of a ConfigMap-style functionConfig, reporting warnings to
+// onWarning. Supported keys: env, nameSuffix, maxNameLength,
+// annotationPrefix, allowedHooks, flattenLists, profile, strictOrdering,
+// envEventName, envWeightName, envPrefix, envDownward, envMode,
+// envEphemeral, nameTemplate.
+func (p *Processor) withFunctionConfig(fnConfig *yaml.Node, onWarning func(Warning)) (*Processor, error) {
+	cfg := p.cfg
+	cfg.OnWarning = onWarning
+	if fnConfig == nil {
+		return NewProcessor(cfg), nil
+	}
+
+	data := mappingValue(fnConfig, "data")
+	if data == nil || data.Kind != yaml.MappingNode {
+		return NewProcessor(cfg), nil
+	}
+
+	for i := 0; i < len(data.Content); i += 2 {
+		key := data.Content[i].Value
+		value := strings.TrimSpace(data.Content[i+1].Value)
+
+		switch key {
+		case "env":
+			cfg.EnvDefault = strings.ToLower(value) == "true"
+		case "nameSuffix":
+			cfg.NameSuffixDefault = strings.ToLower(value) != "false"
+		case "maxNameLength":
+			n, err := strconv.Atoi(value)
+			if err != nil {
+				return nil, fmt.Errorf("invalid maxNameLength %q", value)
+			}
+			if err := checkMaxNameLength(n); err != nil {
+				return nil, err
+			}
+			cfg.MaxNameLength = n
+		case "annotationPrefix":
+			if value != "" && !strings.HasSuffix(value, "/") {
+				value += "/"
+			}
+			cfg.AnnotationPrefix = value
+		case "allowedHooks":
+			cfg.AllowedHooks = make(map[string]bool)
+			for _, h := range parseHookEvents(value) {
+				cfg.AllowedHooks[h] = true
+			}
+		case "flattenLists":
+			cfg.FlattenLists = strings.ToLower(value) == "true"
+		case "profile":
+			if !validProfile(value) {
+				return nil, fmt.Errorf("unknown profile %q", value)
+			}
+			cfg.Profile = value
+		case "strictOrdering":
+			cfg.StrictOrdering = strings.ToLower(value) == "true"
+		case "envEventName", "envWeightName", "envPrefix":
+			if value != "" && !envVarNamePattern.MatchString(value) {
+				return nil, fmt.Errorf("invalid %s %q", key, value)
+			}
+			switch key {
+			case "envEventName":
+				cfg.EventEnvVar = value
+			case "envWeightName":
+				cfg.WeightEnvVar = value
+			default:
+				cfg.EnvVarPrefix = value
+			}
+		case "nameTemplate":
+			cfg.NameTemplate = value
+		case "envMode":
+			if !validEnvMode(value) {
+				return nil, fmt.Errorf("unknown env mode %q", value)
+			}
+			cfg.EnvMode = value
+		case "envEphemeral":
+			cfg.EnvEphemeralContainers = strings.ToLower(value) == "true"
+		case "envDownward":
+			var names envNames
+			if err := names.setDownward(strings.Split(value, ",")); err != nil {
+				return nil, err
+			}
+			cfg.DownwardEnv = strings.Split(value, ",")
+		default:
+			return nil, fmt.Errorf("unknown key %q", key)
+		}
+	}
+
+	return NewProcessor(cfg), nil
+}
+
+// itemRef builds the resourceRef for an item.
+func itemRef(item *yaml.Node, res *Resource) *krmResourceRef {
+	ref := &krmResourceRef{Kind: res.Kind, Name: res.Name}
+	if v := mappingValue(item, "apiVersion"); v != nil {
+		ref.APIVersion = v.Value
+	}
+	if metadata := mappingValue(item, "metadata"); metadata != nil {
+		if v := mappingValue(metadata, "namespace"); v != nil {
+			ref.Namespace = v.Value
+		}
+	}
+	return ref
+}
+
+// mappingValue returns the value for key in a mapping node, or nil.
+func mappingValue(node *yaml.Node, key string) *yaml.Node {
+	if node == nil || node.Kind != yaml.MappingNode {
+		return nil
+	}
+	for i := 0; i < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return node.Content[i+1]
+		}
+	}
+	return nil
+}
+
+// setMappingValue sets key to value in a mapping node, appending it if missing.
+func setMappingValue(node *yaml.Node, key string, value *yaml.Node) {
+	for i := 0; i < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			node.Content[i+1] = value
+			return
+		}
+	}
+	node.Content = append(node.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: key}, value)
+}
+
+// removeMappingKey removes key and its value from a mapping node.
+func removeMappingKey(node *yaml.Node, key string) {
+	for i := 0; i < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			node.Content = append(node.Content[:i], node.Content[i+2:]...)
+			return
+		}
+	}
+}
@@ -0,0 +1,210 @@
+package hook
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestProcessResourceList(t *testing.T) {
+	input := `apiVersion: config.kubernetes.io/v1
+kind: ResourceList
+functionConfig:
+  apiVersion: v1
+  kind: ConfigMap
+  metadata:
+    name: helm-hooks
+  data:
+    env: "false"
+items:
+- apiVersion: batch/v1
+  kind: Job
+  metadata:
+    name: myapp-migration
+    annotations:
+      helm.sh/hook: pre-install,post-upgrade
+      helm.sh/hook-weights: "-100,200"
+      config.kubernetes.io/index: '0'
+      internal.config.kubernetes.io/path: 'job.yaml'
+  spec:
+    template:
+      spec:
+        containers:
+        - name: migrate
+          image: busybox
+- apiVersion: v1
+  kind: ConfigMap
+  metadata:
+    name: myapp-config
+`
+
+	output, err := ProcessResourceList([]byte(input))
+	if err != nil {
+		t.Fatalf("ProcessResourceList failed: %v", err)
+	}
+
+	result := string(output)
+	for _, want := range []string{
+		"kind: ResourceList",
+		"name: myapp-migration-pre-install",
+		"name: myapp-migration-post-upgrade",
+		"name: myapp-config",
+		"internal.config.kubernetes.io/path: 'job.yaml'",
+	} {
+		if !strings.Contains(result, want) {
+			t.Errorf("Expected %q in output:\n%s", want, result)
+		}
+	}
+	if strings.Contains(result, "config.kubernetes.io/index") {
+		t.Error("Index annotations should be removed from split clones")
+	}
+	if strings.Contains(result, "HELM_HOOK_EVENT") {
+		t.Error("functionConfig env=false should disable env injection")
+	}
+	if strings.Contains(result, "results:") {
+		t.Error("Expected no results for valid input")
+	}
+}
+
+func TestProcessResourceList_Results(t *testing.T) {
+	input := `apiVersion: config.kubernetes.io/v1
+kind: ResourceList
+items:
+- apiVersion: batch/v1
+  kind: Job
+  metadata:
+    name: myapp-init
+    namespace: prod
+    annotations:
+      helm.sh/hook: pre-instal
+`
+
+	output, err := ProcessResourceList([]byte(input))
+	if !errors.Is(err, ErrFunctionFailed) {
+		t.Fatalf("Expected ErrFunctionFailed, got: %v", err)
+	}
+
+	result := string(output)
+	for _, want := range []string{
+		"results:",
+		`message: invalid hook "pre-instal"`,
+		"severity: error",
+		"namespace: prod",
+		"path: metadata.annotations.helm.sh/hook",
+	} {
+		if !strings.Contains(result, want) {
+			t.Errorf("Expected %q in output:\n%s", want, result)
+		}
+	}
+}
+
+func TestIsResourceList(t *testing.T) {
+	if !IsResourceList([]byte("apiVersion: config.kubernetes.io/v1\nkind: ResourceList\n")) {
+		t.Error("Expected ResourceList to be detected")
+	}
+	if IsResourceList([]byte("kind: List\nitems:\n- kind: ResourceList\n")) {
+		t.Error("Nested kind should not be detected as ResourceList")
+	}
+	if IsResourceList([]byte("kind: ResourceList\nitems: []\n")) {
+		t.Error("ResourceList without a config.kubernetes.io apiVersion should not be detected")
+	}
+	if !IsResourceList([]byte("# fn input\n---\napiVersion: config.kubernetes.io/v1\nkind: ResourceList\n")) {
+		t.Error("Expected ResourceList after a leading separator to be detected")
+	}
+
+	// A rendered stream whose later document is a ResourceList
+	stream := `apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: config
+---
+apiVersion: config.kubernetes.io/v1
+kind: ResourceList
+items: []
+`
+	if IsResourceList([]byte(stream)) {
+		t.Error("Only the first document should be inspected")
+	}
+}
+
+func TestProcessResourceList_MultipleDocuments(t *testing.T) {
+	input := `apiVersion: config.kubernetes.io/v1
+kind: ResourceList
+items: []
+---
+apiVersion: batch/v1
+kind: Job
+metadata:
+  name: dropped
+`
+
+	_, err := ProcessResourceList([]byte(input))
+	if err == nil || !strings.Contains(err.Error(), "more than one document") {
+		t.Errorf("Expected multiple documents to be rejected, got: %v", err)
+	}
+
+	if _, err := ProcessResourceList([]byte("apiVersion: config.kubernetes.io/v1\nkind: ResourceList\nitems: []\n---\n")); err != nil {
+		t.Errorf("Expected a trailing separator to be accepted, got: %v", err)
+	}
+}
+
+func TestProcessResourceList_InvalidMaxNameLength(t *testing.T) {
+	input := `apiVersion: config.kubernetes.io/v1
+kind: ResourceList
+functionConfig:
+  apiVersion: v1
+  kind: ConfigMap
+  data:
+    maxNameLength: "20"
+items: []
+`
+
+	_, err := ProcessResourceList([]byte(input))
+	if err == nil || !strings.Contains(err.Error(), "invalid maxNameLength 20") {
+		t.Errorf("Expected invalid maxNameLength error, got: %v", err)
+	}
+}
+
+func TestProcessResourceList_StrictOrderingEnvFrom(t *testing.T) {
+	// The env ConfigMap of migrate shares weight 4 with schema, which
+	// strict ordering allows for generated resources
+	input := `apiVersion: config.kubernetes.io/v1
+kind: ResourceList
+functionConfig:
+  apiVersion: v1
+  kind: ConfigMap
+  data:
+    strictOrdering: "true"
+    envMode: envFrom
+items:
+- apiVersion: batch/v1
+  kind: Job
+  metadata:
+    name: schema
+    annotations:
+      helm.sh/hook: pre-install
+      helm.sh/hook-weight: "4"
+      helm.sh/hook-env: "false"
+- apiVersion: batch/v1
+  kind: Job
+  metadata:
+    name: migrate
+    annotations:
+      helm.sh/hook: pre-install
+      helm.sh/hook-weight: "5"
+  spec:
+    template:
+      spec:
+        containers:
+        - name: migrate
+          image: busybox
+`
+
+	output, err := ProcessResourceList([]byte(input))
+	if err != nil {
+		t.Fatalf("ProcessResourceList failed: %v\n%s", err, output)
+	}
+	if !strings.Contains(string(output), "name: migrate-env") || strings.Contains(string(output), "results:") {
+		t.Errorf("Expected the env ConfigMap and no results:\n%s", output)
+	}
+}
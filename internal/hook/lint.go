@@ -0,0 +1,162 @@
+package hook
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Problem describes a single issue found while linting a document.
+type Problem struct {
+	// Document is the 1-based position of the document in the input stream.
+	Document int
+	Kind     string
+	Name     string
+	// Annotation is the annotation key the problem relates to, if known.
+	Annotation string
+	Message    string
+}
+
+// String formats the problem as "document N: Kind/name: annotation: message".
+func (p Problem) String() string {
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "document %d", p.Document)
+	if p.Kind != "" || p.Name != "" {
+		fmt.Fprintf(&buf, ": %s/%s", p.Kind, p.Name)
+	}
+	if p.Annotation != "" {
+		fmt.Fprintf(&buf, ": %s", p.Annotation)
+	}
+	fmt.Fprintf(&buf, ": %s", p.Message)
+	return buf.String()
+}
+
+// Lint validates every document in input using the default configuration.
+func Lint(input []byte) []Problem {
+	return defaultProcessor.Lint(input)
+}
+
+// Lint validates every document in input without producing output.
+// Unlike Process, it does not stop at the first error: all problems,
+// including YAML syntax errors, are collected and returned.
+//
+// Documents that pass are then processed together and checked the way
+// ProcessStream checks a release, e.g. for duplicate objects.
+func (p *Processor) Lint(input []byte) []Problem {
+	p = p.forStream()
+	reader := newDocumentReader(bytes.NewReader(input))
+	deps := p.newDependencyResolver()
+	var problems []Problem
+
+	type lintedDoc struct {
+		index     int
+		node      *yaml.Node
+		dependent bool
+	}
+	var valid []lintedDoc
+
+	for index := 1; ; index++ {
+		raw, err := reader.next()
+		if err != nil {
+			if !errors.Is(err, io.EOF) {
+				problems = append(problems, Problem{Document: index, Message: err.Error()})
+			}
+			break
+		}
+
+		var node yaml.Node
+		if err := yaml.Unmarshal(raw, &node); err != nil {
+			problems = append(problems, Problem{
+				Document: index,
+				Message:  fmt.Sprintf("parsing YAML: %v", err),
+			})
+			continue
+		}
+
+		docProblems := p.lintDocument(&node, index)
+		problems = append(problems, docProblems...)
+		dependent := deps.add(&node, index)
+		if len(docProblems) == 0 {
+			valid = append(valid, lintedDoc{index: index, node: &node, dependent: dependent})
+		}
+	}
+
+	// Check dependencies across documents
+	depsErr := deps.resolve()
+	if err := depsErr; err != nil {
+		var depErr *dependencyError
+		if errors.As(err, &depErr) {
+			problems = append(problems, Problem{
+				Document:   depErr.index,
+				Kind:       depErr.res.Kind,
+				Name:       depErr.res.Name,
+				Annotation: p.cfg.annotationKey(annotationHookDependsOn),
+				Message:    depErr.err.Error(),
+			})
+		} else {
+			problems = append(problems, Problem{Message: err.Error()})
+		}
+	}
+
+	// Check the release as a whole. Dependent documents are skipped if
+	// their weights could not be resolved.
+	release := p.newReleaseValidator()
+	for _, doc := range valid {
+		if doc.dependent && depsErr != nil {
+			continue
+		}
+		outputs, err := p.processDocument(doc.node)
+		if err != nil {
+			continue
+		}
+		if outputs == nil {
+			outputs = []*yaml.Node{doc.node}
+		}
+		problems = append(problems, release.add(doc.index, outputs)...)
+	}
+
+	return problems
+}
+
+// lintDocument runs the annotation checks of processing for a single
+// document, then the full processDocument pipeline on a copy if those
+// checks pass.
+func (p *Processor) lintDocument(node *yaml.Node, index int) []Problem {
+	res, err := parseResource(node)
+	if err != nil {
+		return []Problem{{Document: index, Message: err.Error()}}
+	}
+
+	// Lint each item of a List document
+	if items := listItems(node, res); items != nil {
+		var problems []Problem
+		for _, item := range items.Content {
+			problems = append(problems, p.lintDocument(item, index)...)
+		}
+		return problems
+	}
+
+	weightsKey := p.cfg.annotationKey(annotationHookWeights)
+	_, hasHook := res.Annotations[annotationHook]
+	_, hasWeights := res.Annotations[weightsKey]
+	if (!hasHook && !hasWeights) || !p.cfg.kindSelected(res.Kind) {
+		return nil
+	}
+
+	// The checks processing runs, reporting every problem
+	if _, problems := p.parseHookConfig(res); len(problems) > 0 {
+		return problems.problems(index, res)
+	}
+
+	// Run the full pipeline on a copy to catch anything else
+	var problems problemList
+	if cloned, err := cloneNode(node); err != nil {
+		problems.add("", err)
+	} else if _, err := p.processDocument(cloned); err != nil {
+		problems.add("", err)
+	}
+	return problems.problems(index, res)
+}
@@ -0,0 +1,111 @@
+package hook
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestLint_ReportsAllProblems(t *testing.T) {
+	input := `apiVersion: batch/v1
+kind: Job
+metadata:
+  name: myapp-init
+  annotations:
+    helm.sh/hook: pre-install,bogus,pre-install
+---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: myapp-config
+---
+apiVersion: batch/v1
+kind: Job
+metadata:
+  name: myapp-cleanup
+  annotations:
+    helm.sh/hook: pre-delete,post-delete
+    helm.sh/hook-weights: "pre-delete=abc"
+    helm.sh/hook-delete-policies: "post-delete=sometimes"
+`
+
+	problems := Lint([]byte(input))
+	if len(problems) != 4 {
+		t.Fatalf("Expected 4 problems, got %d: %v", len(problems), problems)
+	}
+
+	expected := []struct {
+		document   int
+		name       string
+		annotation string
+		message    string
+	}{
+		{1, "myapp-init", annotationHook, "invalid hook"},
+		{1, "myapp-init", annotationHook, "duplicate hook"},
+		{3, "myapp-cleanup", annotationHookWeights, "invalid weight"},
+		{3, "myapp-cleanup", annotationHookDeletePolicies, "unknown policy"},
+	}
+	for i, e := range expected {
+		p := problems[i]
+		if p.Document != e.document || p.Name != e.name || p.Annotation != e.annotation {
+			t.Errorf("Problem %d: expected document %d %s %s, got %+v", i, e.document, e.name, e.annotation, p)
+		}
+		if !strings.Contains(p.Message, e.message) {
+			t.Errorf("Problem %d: expected message containing %q, got %q", i, e.message, p.Message)
+		}
+	}
+}
+
+func TestLint_Valid(t *testing.T) {
+	input := `apiVersion: batch/v1
+kind: Job
+metadata:
+  name: myapp-migration
+  annotations:
+    helm.sh/hook: pre-install,post-upgrade
+    helm.sh/hook-weights: "-100,200"
+`
+
+	if problems := Lint([]byte(input)); len(problems) != 0 {
+		t.Errorf("Expected no problems, got %v", problems)
+	}
+}
+
+func TestProblem_String(t *testing.T) {
+	p := Problem{Document: 2, Kind: "Job", Name: "x", Annotation: annotationHook, Message: "invalid hook \"y\""}
+	expected := `document 2: Job/x: helm.sh/hook: invalid hook "y"`
+	if p.String() != expected {
+		t.Errorf("Expected %q, got %q", expected, p.String())
+	}
+}
+
+func TestValidation_SharedByProcessAndLint(t *testing.T) {
+	annotations := `    helm.sh/hook: pre-install,bogus
+    helm.sh/hook-weights: "pre-install=abc"
+    helm.sh/hook-env-mode: volume
+`
+	input := dependsJob("myapp", annotations)
+	want := []string{`invalid hook "bogus"`, "invalid weight", `unknown env mode "volume"`}
+
+	problems := Lint([]byte(input))
+	if len(problems) != len(want) {
+		t.Fatalf("Expected %d problems, got %v", len(want), problems)
+	}
+
+	// Process and ValidateAnnotations report the same problems
+	_, processErr := Process([]byte(input))
+	validateErr := ValidateAnnotations(map[string]string{
+		annotationHook:        "pre-install,bogus",
+		annotationHookWeights: "pre-install=abc",
+		annotationHookEnvMode: "volume",
+	}, "myapp")
+	for i, w := range want {
+		if !strings.Contains(problems[i].Message, w) {
+			t.Errorf("Problem %d: expected %q, got %v", i, w, problems[i])
+		}
+		for _, err := range []error{processErr, validateErr} {
+			if err == nil || !strings.Contains(err.Error(), w) {
+				t.Errorf("Expected error containing %q, got: %v", w, err)
+			}
+		}
+	}
+}
@@ -0,0 +1,65 @@
+package hook
+
+import (
+	"fmt"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// listItems returns the items sequence of a List document (v1/List or
+// any *List kind), or nil if node is not a list.
+func listItems(node *yaml.Node, res *Resource) *yaml.Node {
+	if !strings.HasSuffix(res.Kind, "List") {
+		return nil
+	}
+
+	content := node
+	if node.Kind == yaml.DocumentNode && len(node.Content) > 0 {
+		content = node.Content[0]
+	}
+
+	items := mappingValue(content, "items")
+	if items == nil || items.Kind != yaml.SequenceNode {
+		return nil
+	}
+	return items
+}
+
+// processList applies hook processing to every item of a List document.
+// Multi-hook items are replaced by their split clones within the list.
+// With Config.FlattenLists, every item is returned as its own document
+// instead. Returns nil if the list is left unchanged.
+func (p *Processor) processList(node *yaml.Node, res *Resource, items *yaml.Node) ([]*yaml.Node, error) {
+	var newItems []*yaml.Node
+	changed := false
+
+	for i, item := range items.Content {
+		processed, err := p.processDocument(item)
+		if err != nil {
+			return nil, fmt.Errorf("%s %q item %d: %w", res.Kind, res.Name, i, err)
+		}
+		if processed == nil {
+			newItems = append(newItems, item)
+			continue
+		}
+
+		changed = true
+		for _, out := range processed {
+			if out.Kind == yaml.DocumentNode && len(out.Content) > 0 {
+				out = out.Content[0]
+			}
+			newItems = append(newItems, out)
+		}
+	}
+
+	if p.cfg.FlattenLists {
+		return newItems, nil
+	}
+	if !changed {
+		return nil, nil
+	}
+
+	items.Content = newItems
+	return []*yaml.Node{node}, nil
+}
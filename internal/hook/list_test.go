@@ -0,0 +1,90 @@
+package hook
+
+import (
+	"strings"
+	"testing"
+)
+
+const listInput = `apiVersion: v1
+kind: List
+items:
+  - apiVersion: v1
+    kind: ConfigMap
+    metadata:
+      name: myapp-config
+  - apiVersion: batch/v1
+    kind: Job
+    metadata:
+      name: myapp-migration
+      annotations:
+        helm.sh/hook: pre-install,post-upgrade
+        helm.sh/hook-weights: "-100,200"
+    spec:
+      template:
+        spec:
+          containers:
+            - name: migrate
+              image: busybox
+`
+
+func TestProcess_ListSplitsWithinList(t *testing.T) {
+	output, err := Process([]byte(listInput))
+	if err != nil {
+		t.Fatalf("Process failed: %v", err)
+	}
+
+	result := string(output)
+	if strings.Contains(result, "---") {
+		t.Errorf("Expected a single List document, got:\n%s", result)
+	}
+	if strings.Count(result, "kind: Job") != 2 {
+		t.Errorf("Expected 2 Job items, got:\n%s", result)
+	}
+	for _, want := range []string{"kind: List", "name: myapp-config", "name: myapp-migration-pre-install", "name: myapp-migration-post-upgrade", "HELM_HOOK_EVENT"} {
+		if !strings.Contains(result, want) {
+			t.Errorf("Expected %q in output:\n%s", want, result)
+		}
+	}
+}
+
+func TestProcess_ListFlatten(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.FlattenLists = true
+
+	output, err := NewProcessor(cfg).Process([]byte(listInput))
+	if err != nil {
+		t.Fatalf("Process failed: %v", err)
+	}
+
+	docs := strings.Split(string(output), "---\n")
+	if len(docs) != 3 {
+		t.Fatalf("Expected 3 documents, got %d:\n%s", len(docs), output)
+	}
+	if strings.Contains(string(output), "kind: List") {
+		t.Error("List wrapper should be removed when flattening")
+	}
+	if !strings.HasPrefix(docs[0], "apiVersion: v1\nkind: ConfigMap") {
+		t.Errorf("Expected ConfigMap as first document, got:\n%s", docs[0])
+	}
+}
+
+func TestProcess_ListUnchanged(t *testing.T) {
+	input := "apiVersion: v1\nkind: ConfigMapList\nitems:\n    - kind: ConfigMap\n      metadata: {name: a}\n"
+
+	output, err := Process([]byte(input))
+	if err != nil {
+		t.Fatalf("Process failed: %v", err)
+	}
+	if string(output) != input {
+		t.Errorf("Expected list without hooks to be copied verbatim, got:\n%s", output)
+	}
+}
+
+func TestLint_ListItems(t *testing.T) {
+	input := strings.Replace(listInput, "pre-install,post-upgrade", "pre-install,bogus", 1)
+
+	problems := Lint([]byte(input))
+	if len(problems) != 1 || problems[0].Name != "myapp-migration" || !strings.Contains(problems[0].Message, "invalid hook") {
+		t.Errorf("Expected invalid hook problem on list item, got %v", problems)
+	}
+}
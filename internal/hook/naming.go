@@ -3,7 +3,10 @@ package hook
 import (
 	"crypto/sha256"
 	"encoding/hex"
+	"fmt"
+	"regexp"
 	"strings"
+	"text/template"
 )
 
 const (
@@ -11,61 +14,74 @@ const (
 	maxNameLength = 63
 	// hashLength is the length of truncation hash suffix
 	hashLength = 8
+	// minNameLength is the shortest name length limit that still fits a
+	// truncated name with the longest event: a-post-rollback-<hash>
+	minNameLength = 1 + len("-post-rollback") + 1 + hashLength
 )
 
 // GenerateName creates a hook-specific name from the original name and hook event.
 // It handles the 63-character Kubernetes name limit with deterministic hashing.
 func GenerateName(originalName, hookEvent string) string {
+	name, _, _ := generateName(originalName, hookEvent, defaultNameRule)
+	return name
+}
+
+// GenerateNameForKind is GenerateName with the name rule of a kind, e.g.
+// the 52-character limit of CronJobs. It fails if the name is not valid
+// for the kind.
+func GenerateNameForKind(kind, originalName, hookEvent string) (string, error) {
+	rule, err := DefaultConfig().nameRule(kind)
+	if err != nil {
+		return "", err
+	}
+	name, _, err := generateName(originalName, hookEvent, rule)
+	return name, err
+}
+
+// generateName is GenerateName with a name rule. It also reports whether
+// the name had to be truncated.
+func generateName(originalName, hookEvent string, rule nameRule) (string, bool, error) {
 	// Create the suffixed name
 	suffixedName := originalName + "-" + hookEvent
 
 	// If under limit, use as-is
-	if len(suffixedName) <= maxNameLength {
-		return suffixedName
+	name, truncated := suffixedName, false
+	if len(suffixedName) > rule.maxLen {
+		// Need to truncate with hash
+		name, truncated = truncateWithHash(originalName, hookEvent, rule.maxLen), true
 	}
-
-	// Need to truncate with hash
-	return truncateWithHash(originalName, hookEvent)
+	return name, truncated, rule.validate(name)
 }
 
 // truncateWithHash creates a truncated name with a deterministic hash suffix.
 // Format: <truncated-name>-<hook>-<hash>
-func truncateWithHash(originalName, hookEvent string) string {
+func truncateWithHash(originalName, hookEvent string, maxLen int) string {
 	// Calculate hash of original full name
 	fullName := originalName + "-" + hookEvent
 	hash := sha256.Sum256([]byte(fullName))
 	hashStr := hex.EncodeToString(hash[:])[:hashLength]
 
-	// Calculate available space for base name
 	// Format: <base>-<hook>-<hash>
 	hookPart := "-" + hookEvent
 	hashPart := "-" + hashStr
-	separatorLen := len(hookPart) + len(hashPart)
-
-	availableLen := maxNameLength - separatorLen
-	if availableLen < 1 {
-		// Hook name too long, just use hash
-		availableLen = maxNameLength - len(hashPart) - 1
-		if availableLen < 1 {
-			availableLen = 1
-		}
-	}
 
-	// Truncate the original name
-	truncatedBase := truncateName(originalName, availableLen)
+	// The hash keeps names of distinct inputs apart, so it is never cut.
+	// Without room for it, the name is left too long for validation to
+	// report.
+	if maxLen < 1+len(hashPart) {
+		return truncateName(originalName, 1) + hashPart
+	}
 
-	// Build final name
-	result := truncatedBase + hookPart + hashPart
-	
-	// Safety check - if still too long, aggressively truncate
-	if len(result) > maxNameLength {
-		result = result[:maxNameLength]
+	// Keep at least one character of the base name; shorten or drop the
+	// hook part to make room
+	if eventLen := maxLen - len(hashPart) - 1; eventLen < len(hookPart) {
+		hookPart = strings.TrimRight(hookPart[:max(eventLen, 0)], "-")
 	}
 
-	// Ensure valid DNS name (no trailing dash)
-	result = strings.TrimRight(result, "-")
+	// Truncate the original name
+	truncatedBase := truncateName(originalName, maxLen-len(hookPart)-len(hashPart))
 
-	return result
+	return truncatedBase + hookPart + hashPart
 }
 
 // truncateName truncates a name to the given length, avoiding trailing dashes.
@@ -75,7 +91,189 @@ func truncateName(name string, maxLen int) string {
 	}
 
 	truncated := name[:maxLen]
-	// Remove trailing dashes
-	truncated = strings.TrimRight(truncated, "-")
+	// Remove trailing dashes and dots, which would end a DNS label
+	truncated = strings.TrimRight(truncated, "-.")
 	return truncated
 }
+
+// eventShortCodes are the {{.EventShort}} codes of hook events in name
+// templates.
+var eventShortCodes = map[string]string{
+	"pre-install":   "pi",
+	"pre-upgrade":   "pu",
+	"pre-rollback":  "pr",
+	"pre-delete":    "pd",
+	"post-install":  "poi",
+	"post-upgrade":  "pou",
+	"post-rollback": "por",
+	"post-delete":   "pod",
+	"test":          "t",
+	"test-success":  "ts",
+	"test-failure":  "tf",
+}
+
+// dns1123SubdomainPattern matches a DNS-1123 subdomain, the format of
+// most Kubernetes resource names.
+var dns1123SubdomainPattern = regexp.MustCompile(`^[a-z0-9]([-a-z0-9]*[a-z0-9])?(\.[a-z0-9]([-a-z0-9]*[a-z0-9])?)*$`)
+
+// dns1035LabelPattern matches a DNS-1035 label, the format of Service
+// names: no dots and a leading letter.
+var dns1035LabelPattern = regexp.MustCompile(`^[a-z]([-a-z0-9]*[a-z0-9])?$`)
+
+// nameRule is the length limit and format of the names of a kind.
+type nameRule struct {
+	maxLen int
+	// label requires a DNS-1035 label instead of a DNS-1123 subdomain.
+	label bool
+}
+
+// defaultNameRule applies to kinds without an entry in kindNameRules.
+// Most workloads copy their name into the 63-character label values of
+// their pods, e.g. job-name.
+var defaultNameRule = nameRule{maxLen: maxNameLength}
+
+// kindNameRules are the name rules of kinds whose limits differ from the
+// default.
+var kindNameRules = map[string]nameRule{
+	// The CronJob controller appends an 11-character suffix to the names
+	// of its Jobs.
+	"CronJob":   {maxLen: 52},
+	"ConfigMap": {maxLen: 253},
+	"Secret":    {maxLen: 253},
+	"Service":   {maxLen: maxNameLength, label: true},
+}
+
+// validate checks that a name fits the rule.
+func (r nameRule) validate(name string) error {
+	if len(name) > r.maxLen {
+		return fmt.Errorf("name %q is longer than %d characters", name, r.maxLen)
+	}
+	if r.label {
+		if !dns1035LabelPattern.MatchString(name) {
+			return fmt.Errorf("name %q is not a valid DNS-1035 label: lowercase alphanumerics and '-', starting with a letter", name)
+		}
+		return nil
+	}
+	if !dns1123SubdomainPattern.MatchString(name) {
+		return fmt.Errorf("name %q is not a valid DNS-1123 subdomain: lowercase alphanumerics, '-' and '.'", name)
+	}
+	return nil
+}
+
+// nameTemplateData is the data of a name template, e.g.
+// {{.Name}}-{{.EventShort}}.
+type nameTemplateData struct {
+	// Name is the original resource name.
+	Name string
+	Kind string
+	// Event is the hook event, e.g. post-rollback.
+	Event string
+	// EventShort is the short code of the event, e.g. por.
+	EventShort string
+	// Index is the position of the event in helm.sh/hook, from 0.
+	Index  int
+	Weight int
+}
+
+// nameTemplate returns the name template of a resource, from
+// helm.sh/hook-name-template or Config.NameTemplate, and the annotation
+// it came from ("" for the configuration). It returns nil if none is set.
+func (p *Processor) nameTemplate(res *Resource) (*template.Template, string, error) {
+	key := p.cfg.annotationKey(annotationHookNameTemplate)
+	text, ok := res.Annotations[key]
+	if !ok {
+		text, key = p.cfg.NameTemplate, ""
+	}
+	if strings.TrimSpace(text) == "" {
+		return nil, key, nil
+	}
+
+	tmpl, err := template.New("name").Option("missingkey=error").Parse(text)
+	if err != nil {
+		return nil, key, nameTemplateError(key, "invalid name template: %v", err)
+	}
+	return tmpl, key, nil
+}
+
+// nameTemplateError returns an error about the name template from the
+// annotation key, or from the configuration if key is empty.
+func nameTemplateError(key, format string, args ...interface{}) error {
+	if key == "" {
+		return fmt.Errorf(format, args...)
+	}
+	return annotationErrorf(key, format, args...)
+}
+
+// splitNames returns the name of each clone of a split resource and
+// whether it was truncated to fit the length limit. Names rendered from
+// a name template must be unique. All names must be valid for the kind of
+// the resource.
+func (p *Processor) splitNames(res *Resource, specs []hookSpec, nameSuffixEnabled bool) ([]string, []bool, error) {
+	names := make([]string, len(specs))
+	truncated := make([]bool, len(specs))
+	if !nameSuffixEnabled {
+		for i := range specs {
+			names[i] = res.Name
+		}
+		return names, truncated, nil
+	}
+
+	tmpl, key, err := p.nameTemplate(res)
+	if err != nil {
+		return nil, nil, err
+	}
+	rule, err := p.cfg.nameRule(res.Kind)
+	if err != nil {
+		return nil, nil, err
+	}
+	if tmpl == nil {
+		for i, spec := range specs {
+			if names[i], truncated[i], err = generateName(res.Name, spec.event, rule); err != nil {
+				return nil, nil, fmt.Errorf("%s name for %s: %w", res.Kind, spec.event, err)
+			}
+		}
+		return names, truncated, nil
+	}
+
+	seen := make(map[string]string)
+	for i, spec := range specs {
+		data := nameTemplateData{
+			Name:       res.Name,
+			Kind:       res.Kind,
+			Event:      spec.event,
+			EventShort: eventShortCodes[spec.event],
+			Index:      i,
+			Weight:     spec.weight,
+		}
+		var buf strings.Builder
+		if err := tmpl.Execute(&buf, data); err != nil {
+			return nil, nil, nameTemplateError(key, "rendering name template: %v", err)
+		}
+
+		name := strings.TrimSpace(buf.String())
+		if !dns1123SubdomainPattern.MatchString(name) {
+			return nil, nil, nameTemplateError(key, "name template renders %q for %s, which is not a valid DNS-1123 name", name, spec.event)
+		}
+		names[i], truncated[i] = truncateRenderedName(name, rule.maxLen)
+		if err := rule.validate(names[i]); err != nil {
+			return nil, nil, nameTemplateError(key, "name template renders an invalid %s name for %s: %v", res.Kind, spec.event, err)
+		}
+		if other, dup := seen[names[i]]; dup {
+			return nil, nil, nameTemplateError(key, "name template renders %q for both %s and %s", names[i], other, spec.event)
+		}
+		seen[names[i]] = spec.event
+	}
+	return names, truncated, nil
+}
+
+// truncateRenderedName shortens a rendered name to maxLen by replacing
+// its end with a hash of the full name, and reports whether it did.
+func truncateRenderedName(name string, maxLen int) (string, bool) {
+	if len(name) <= maxLen {
+		return name, false
+	}
+	hash := sha256.Sum256([]byte(name))
+	hashPart := "-" + hex.EncodeToString(hash[:])[:hashLength]
+	base := truncateName(name, max(maxLen-len(hashPart), 1))
+	return base + hashPart, true
+}
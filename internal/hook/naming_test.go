@@ -1,6 +1,7 @@
 package hook
 
 import (
+	"strings"
 	"testing"
 )
 
@@ -61,3 +62,199 @@ func TestGenerateName_DifferentHooks(t *testing.T) {
 		t.Error("Different hooks should produce different names")
 	}
 }
+
+func TestProcess_NameTemplate(t *testing.T) {
+	input := dependsJob("myapp-db-migration", `    helm.sh/hook: pre-install,post-rollback
+    helm.sh/hook-name-template: "{{.Name}}-{{.EventShort}}"
+`)
+
+	output, err := Process([]byte(input))
+	if err != nil {
+		t.Fatalf("Process failed: %v", err)
+	}
+	result := string(output)
+	for _, want := range []string{"name: myapp-db-migration-pi\n", "name: myapp-db-migration-por\n"} {
+		if !strings.Contains(result, want) {
+			t.Errorf("Expected %q in output:\n%s", want, result)
+		}
+	}
+
+	cfg := DefaultConfig()
+	cfg.NameTemplate = "{{.Name}}-{{.Index}}"
+	output, err = NewProcessor(cfg).Process([]byte(dependsJob("seed", `    helm.sh/hook: post-install,post-upgrade
+`)))
+	if err != nil {
+		t.Fatalf("Process failed: %v", err)
+	}
+	if !strings.Contains(string(output), "name: seed-0\n") || !strings.Contains(string(output), "name: seed-1\n") {
+		t.Errorf("Expected index names, got:\n%s", output)
+	}
+}
+
+func TestTruncateRenderedName(t *testing.T) {
+	name := strings.Repeat("a", 60) + "-post-rollback"
+	got, truncated := truncateRenderedName(name, 63)
+	if !truncated || len(got) != 63 || !strings.HasPrefix(got, strings.Repeat("a", 54)+"-") {
+		t.Errorf("truncateRenderedName = %q, %v", got, truncated)
+	}
+	if again, _ := truncateRenderedName(name, 63); again != got {
+		t.Errorf("Expected a deterministic name, got %q and %q", got, again)
+	}
+}
+
+func TestProcess_InvalidNameTemplate(t *testing.T) {
+	tests := []struct {
+		name     string
+		template string
+		want     string
+	}{
+		{name: "parse error", template: "{{.Name", want: "invalid name template"},
+		{name: "unknown field", template: "{{.Nmae}}-{{.Event}}", want: "rendering name template"},
+		{name: "invalid name", template: "{{.Name}}_{{.EventShort}}", want: `name template renders "job_pi" for pre-install, which is not a valid DNS-1123 name`},
+		{name: "duplicate", template: "{{.Name}}-{{.Weight}}", want: `name template renders "job-0" for both pre-install and post-install`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			input := dependsJob("job", "    helm.sh/hook: pre-install,post-install\n    helm.sh/hook-name-template: \""+tt.template+"\"\n")
+			_, err := Process([]byte(input))
+			if err == nil || !strings.Contains(err.Error(), "helm.sh/hook-name-template: "+tt.want) {
+				t.Errorf("Expected error containing %q, got: %v", tt.want, err)
+			}
+		})
+	}
+}
+
+func TestGenerateNameForKind(t *testing.T) {
+	long := strings.Repeat("a", 60)
+	tests := []struct {
+		kind    string
+		base    string
+		maxLen  int
+		wantErr string
+	}{
+		{kind: "Job", base: long, maxLen: 63},
+		{kind: "CronJob", base: long, maxLen: 52},
+		{kind: "ConfigMap", base: long + "-" + long, maxLen: 253},
+		{kind: "Service", base: long, maxLen: 63},
+		{kind: "Service", base: "9svc", wantErr: "not a valid DNS-1035 label"},
+		{kind: "Service", base: "db.local", wantErr: "not a valid DNS-1035 label"},
+		{kind: "Job", base: "Migrate", wantErr: "not a valid DNS-1123 subdomain"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.kind+"/"+tt.base, func(t *testing.T) {
+			name, err := GenerateNameForKind(tt.kind, tt.base, "pre-install")
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("Expected error containing %q, got %q, %v", tt.wantErr, name, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("GenerateNameForKind failed: %v", err)
+			}
+			if len(name) > tt.maxLen || !strings.HasPrefix(name, "a") {
+				t.Errorf("Expected a name of at most %d characters, got %q (%d)", tt.maxLen, name, len(name))
+			}
+		})
+	}
+}
+
+func TestGenerateName_TruncatedAtDot(t *testing.T) {
+	// The base is cut right after a dot, which must not end up before
+	// the hook suffix.
+	base := strings.Repeat("a", 41) + ".example.com"
+	name := GenerateName(base, "pre-install")
+	if strings.Contains(name, ".-") || !dns1123SubdomainPattern.MatchString(name) {
+		t.Errorf("Expected a valid DNS-1123 name, got %q", name)
+	}
+}
+
+func TestConfig_NameRule(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.MaxNameLength = 40
+	for kind, want := range map[string]int{"Job": 40, "CronJob": 40, "Service": 40, "Secret": 253} {
+		if rule, err := cfg.nameRule(kind); err != nil || rule.maxLen != want {
+			t.Errorf("%s: maxLen = %d (%v), want %d", kind, rule.maxLen, err, want)
+		}
+	}
+	cfg.MaxNameLength = 80
+	if rule, _ := cfg.nameRule("CronJob"); rule.maxLen != 52 {
+		t.Errorf("CronJob: maxLen = %d, want 52", rule.maxLen)
+	}
+	cfg.MaxNameLength = minNameLength - 1
+	if _, err := cfg.nameRule("Job"); err == nil || !strings.Contains(err.Error(), "must be at least 24") {
+		t.Errorf("Expected a too short maxNameLength to be rejected, got: %v", err)
+	}
+}
+
+func TestProcess_MaxNameLengthTooShort(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.MaxNameLength = 20
+	_, err := NewProcessor(cfg).Process([]byte("apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: config\n"))
+	if err == nil || !strings.Contains(err.Error(), "invalid maxNameLength 20") {
+		t.Errorf("Expected invalid maxNameLength error, got: %v", err)
+	}
+}
+
+func TestProcess_SplitInvalidServiceName(t *testing.T) {
+	input := `apiVersion: v1
+kind: Service
+metadata:
+  name: 1st-svc
+  annotations:
+    helm.sh/hook: pre-install,pre-upgrade
+spec:
+  ports:
+    - port: 80
+`
+	_, err := Process([]byte(input))
+	if err == nil || !strings.Contains(err.Error(), "Service name for pre-install") || !strings.Contains(err.Error(), "DNS-1035") {
+		t.Errorf("Expected a DNS-1035 error, got: %v", err)
+	}
+}
+
+func TestTruncateWithHash_SmallLimits(t *testing.T) {
+	bases := []string{"averylongname-alpha", "averylongname-beta"}
+	events := []string{"pre-install", "post-rollback"}
+	for _, maxLen := range []int{10, 12, 20, 24, 30} {
+		seen := make(map[string]string)
+		for _, base := range bases {
+			for _, event := range events {
+				name := truncateWithHash(base, event, maxLen)
+				if len(name) > maxLen || !dns1123SubdomainPattern.MatchString(name) {
+					t.Errorf("maxLen %d: invalid name %q for %s/%s", maxLen, name, base, event)
+				}
+				if other, dup := seen[name]; dup {
+					t.Errorf("maxLen %d: %s/%s and %s both give %q", maxLen, base, event, other, name)
+				}
+				seen[name] = base + "/" + event
+			}
+		}
+	}
+}
+
+func TestProcess_SmallNameLimitDistinctNames(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.MaxNameLength = 24
+	input := dependsJob("averylongname-alpha", "    helm.sh/hook: pre-install,post-rollback\n") + "---\n" +
+		dependsJob("averylongname-beta", "    helm.sh/hook: pre-install,post-rollback\n")
+
+	_, report, err := NewProcessor(cfg).ProcessWithReport([]byte(input))
+	if err != nil {
+		t.Fatalf("Process failed: %v", err)
+	}
+	seen := make(map[string]bool)
+	for _, doc := range report.Documents {
+		for _, out := range doc.Outputs {
+			if len(out.Name) > 24 || seen[out.Name] || !out.Truncated {
+				t.Errorf("Unexpected output name %q (truncated %v)", out.Name, out.Truncated)
+			}
+			seen[out.Name] = true
+		}
+	}
+	if len(seen) != 4 {
+		t.Errorf("Expected 4 distinct names, got %v", seen)
+	}
+}
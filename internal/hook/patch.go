@@ -0,0 +1,470 @@
+package hook
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Valid RFC 6902 JSON Patch operations
+var validPatchOps = map[string]bool{
+	"add":     true,
+	"remove":  true,
+	"replace": true,
+	"move":    true,
+	"copy":    true,
+	"test":    true,
+}
+
+// patchDirective is the strategic-merge key that marks a list item for deletion.
+const patchDirective = "$patch"
+
+// patchOp is a single RFC 6902 JSON Patch operation.
+type patchOp struct {
+	Op    string
+	Path  string
+	From  string
+	Value *yaml.Node
+}
+
+// hookPatch is a per-hook patch: either a list of JSON Patch operations
+// or a strategic-merge fragment.
+type hookPatch struct {
+	ops   []patchOp
+	merge *yaml.Node
+}
+
+// eventPatchKey returns the per-event patch annotation key, e.g. helm.sh/hook-patch-pre-install.
+func (p *Processor) eventPatchKey(hookEvent string) string {
+	return p.cfg.annotationKey(annotationHookPatch) + "-" + hookEvent
+}
+
+// hasHookPatches reports whether a resource declares per-hook patches.
+func (p *Processor) hasHookPatches(res *Resource) bool {
+	for _, h := range sortedValidHooks() {
+		if _, ok := res.Annotations[p.eventPatchKey(h)]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+// removePatchAnnotations removes the processed per-hook patch annotations.
+func (p *Processor) removePatchAnnotations(node *yaml.Node) {
+	for _, h := range sortedValidHooks() {
+		removeAnnotation(node, p.eventPatchKey(h))
+	}
+}
+
+// parseHookPatches reads helm.sh/hook-patch-<event> annotations. Each value
+// is YAML or JSON holding either a JSON Patch (a list of operations) or a
+// strategic-merge fragment (a mapping).
+func (p *Processor) parseHookPatches(res *Resource, hooks []string) (map[string]*hookPatch, error) {
+	patches := make(map[string]*hookPatch)
+
+	isHook := make(map[string]bool)
+	for _, h := range hooks {
+		isHook[h] = true
+	}
+
+	for _, h := range sortedValidHooks() {
+		key := p.eventPatchKey(h)
+		value, ok := res.Annotations[key]
+		if !ok {
+			continue
+		}
+		if !isHook[h] {
+			return nil, annotationErrorf(key, "patch specified for unknown hook %q", h)
+		}
+		patch, err := parseHookPatch(value)
+		if err != nil {
+			return nil, &annotationError{annotation: key, err: err}
+		}
+		patches[h] = patch
+	}
+
+	return patches, nil
+}
+
+// parseHookPatch parses a single patch annotation value.
+func parseHookPatch(value string) (*hookPatch, error) {
+	var root yaml.Node
+	if err := yaml.Unmarshal([]byte(value), &root); err != nil {
+		return nil, fmt.Errorf("invalid YAML: %w", err)
+	}
+	if len(root.Content) == 0 {
+		return nil, fmt.Errorf("empty patch")
+	}
+
+	node := root.Content[0]
+	switch node.Kind {
+	case yaml.MappingNode:
+		return &hookPatch{merge: node}, nil
+	case yaml.SequenceNode:
+		ops := make([]patchOp, 0, len(node.Content))
+		for i, item := range node.Content {
+			op, err := parsePatchOp(item)
+			if err != nil {
+				return nil, fmt.Errorf("operation %d: %w", i+1, err)
+			}
+			ops = append(ops, op)
+		}
+		return &hookPatch{ops: ops}, nil
+	default:
+		return nil, fmt.Errorf("expected a JSON Patch list or a strategic-merge mapping")
+	}
+}
+
+// parsePatchOp parses and validates one JSON Patch operation.
+func parsePatchOp(node *yaml.Node) (patchOp, error) {
+	var op patchOp
+	if node.Kind != yaml.MappingNode {
+		return op, fmt.Errorf("expected a mapping")
+	}
+
+	hasValue := false
+	for i := 0; i < len(node.Content); i += 2 {
+		key, value := node.Content[i].Value, node.Content[i+1]
+		switch key {
+		case "op":
+			op.Op = value.Value
+		case "path":
+			op.Path = value.Value
+		case "from":
+			op.From = value.Value
+		case "value":
+			op.Value = value
+			hasValue = true
+		}
+	}
+
+	if !validPatchOps[op.Op] {
+		return op, fmt.Errorf("unknown op %q", op.Op)
+	}
+	if _, err := parsePointer(op.Path); err != nil {
+		return op, err
+	}
+	switch op.Op {
+	case "add", "replace", "test":
+		if !hasValue {
+			return op, fmt.Errorf("%s requires a value", op.Op)
+		}
+	case "move", "copy":
+		if _, err := parsePointer(op.From); err != nil {
+			return op, fmt.Errorf("from: %w", err)
+		}
+	}
+	return op, nil
+}
+
+// apply patches a resource mapping in place.
+func (hp *hookPatch) apply(node *yaml.Node) error {
+	if hp.merge != nil {
+		mergeNode(node, hp.merge)
+		return nil
+	}
+	for _, op := range hp.ops {
+		if err := applyPatchOp(node, op); err != nil {
+			return fmt.Errorf("%s %s: %w", op.Op, op.Path, err)
+		}
+	}
+	return nil
+}
+
+// applyPatchOp applies a single JSON Patch operation to root.
+func applyPatchOp(root *yaml.Node, op patchOp) error {
+	switch op.Op {
+	case "add":
+		return addAtPointer(root, op.Path, copyNode(op.Value))
+	case "remove":
+		_, err := removeAtPointer(root, op.Path)
+		return err
+	case "replace":
+		return replaceAtPointer(root, op.Path, copyNode(op.Value))
+	case "move":
+		value, err := removeAtPointer(root, op.From)
+		if err != nil {
+			return err
+		}
+		return addAtPointer(root, op.Path, value)
+	case "copy":
+		value, err := getAtPointer(root, op.From)
+		if err != nil {
+			return err
+		}
+		return addAtPointer(root, op.Path, copyNode(value))
+	case "test":
+		value, err := getAtPointer(root, op.Path)
+		if err != nil {
+			return err
+		}
+		if !nodesEqual(value, op.Value) {
+			return fmt.Errorf("test failed")
+		}
+		return nil
+	}
+	return fmt.Errorf("unknown op %q", op.Op)
+}
+
+// parsePointer splits an RFC 6901 JSON Pointer into unescaped tokens.
+// The empty pointer (the whole document) is rejected.
+func parsePointer(path string) ([]string, error) {
+	if !strings.HasPrefix(path, "/") {
+		return nil, fmt.Errorf("invalid path %q, must start with /", path)
+	}
+	tokens := strings.Split(path[1:], "/")
+	for i, t := range tokens {
+		t = strings.ReplaceAll(t, "~1", "/")
+		tokens[i] = strings.ReplaceAll(t, "~0", "~")
+	}
+	return tokens, nil
+}
+
+// resolveParent walks to the container holding the last token of path.
+func resolveParent(root *yaml.Node, path string) (*yaml.Node, string, error) {
+	tokens, err := parsePointer(path)
+	if err != nil {
+		return nil, "", err
+	}
+	node := root
+	for _, token := range tokens[:len(tokens)-1] {
+		node, err = childNode(node, token)
+		if err != nil {
+			return nil, "", err
+		}
+	}
+	return node, tokens[len(tokens)-1], nil
+}
+
+// childNode returns the child of a mapping or sequence named by token.
+func childNode(node *yaml.Node, token string) (*yaml.Node, error) {
+	switch node.Kind {
+	case yaml.MappingNode:
+		for i := 0; i < len(node.Content); i += 2 {
+			if node.Content[i].Value == token {
+				return node.Content[i+1], nil
+			}
+		}
+		return nil, fmt.Errorf("key %q not found", token)
+	case yaml.SequenceNode:
+		index, err := sequenceIndex(node, token, false)
+		if err != nil {
+			return nil, err
+		}
+		return node.Content[index], nil
+	default:
+		return nil, fmt.Errorf("cannot traverse scalar at %q", token)
+	}
+}
+
+// sequenceIndex parses a sequence index token. With allowEnd, "-" and an
+// index equal to the length address the position after the last item.
+func sequenceIndex(seq *yaml.Node, token string, allowEnd bool) (int, error) {
+	if token == "-" && allowEnd {
+		return len(seq.Content), nil
+	}
+	index, err := strconv.Atoi(token)
+	if err != nil || index < 0 {
+		return 0, fmt.Errorf("invalid index %q", token)
+	}
+	limit := len(seq.Content)
+	if allowEnd {
+		limit++
+	}
+	if index >= limit {
+		return 0, fmt.Errorf("index %d out of range", index)
+	}
+	return index, nil
+}
+
+// getAtPointer returns the node at path.
+func getAtPointer(root *yaml.Node, path string) (*yaml.Node, error) {
+	parent, token, err := resolveParent(root, path)
+	if err != nil {
+		return nil, err
+	}
+	return childNode(parent, token)
+}
+
+// addAtPointer sets a mapping key or inserts into a sequence at path.
+func addAtPointer(root *yaml.Node, path string, value *yaml.Node) error {
+	parent, token, err := resolveParent(root, path)
+	if err != nil {
+		return err
+	}
+	switch parent.Kind {
+	case yaml.MappingNode:
+		for i := 0; i < len(parent.Content); i += 2 {
+			if parent.Content[i].Value == token {
+				parent.Content[i+1] = value
+				return nil
+			}
+		}
+		parent.Content = append(parent.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: token}, value)
+		return nil
+	case yaml.SequenceNode:
+		index, err := sequenceIndex(parent, token, true)
+		if err != nil {
+			return err
+		}
+		parent.Content = append(parent.Content, nil)
+		copy(parent.Content[index+1:], parent.Content[index:])
+		parent.Content[index] = value
+		return nil
+	default:
+		return fmt.Errorf("cannot add to scalar")
+	}
+}
+
+// replaceAtPointer replaces the existing mapping value or sequence item
+// at path in place.
+func replaceAtPointer(root *yaml.Node, path string, value *yaml.Node) error {
+	parent, token, err := resolveParent(root, path)
+	if err != nil {
+		return err
+	}
+	switch parent.Kind {
+	case yaml.MappingNode:
+		for i := 0; i < len(parent.Content); i += 2 {
+			if parent.Content[i].Value == token {
+				parent.Content[i+1] = value
+				return nil
+			}
+		}
+		return fmt.Errorf("key %q not found", token)
+	case yaml.SequenceNode:
+		index, err := sequenceIndex(parent, token, false)
+		if err != nil {
+			return err
+		}
+		parent.Content[index] = value
+		return nil
+	default:
+		return fmt.Errorf("cannot replace in scalar")
+	}
+}
+
+// removeAtPointer removes and returns the node at path.
+func removeAtPointer(root *yaml.Node, path string) (*yaml.Node, error) {
+	parent, token, err := resolveParent(root, path)
+	if err != nil {
+		return nil, err
+	}
+	switch parent.Kind {
+	case yaml.MappingNode:
+		for i := 0; i < len(parent.Content); i += 2 {
+			if parent.Content[i].Value == token {
+				value := parent.Content[i+1]
+				parent.Content = append(parent.Content[:i], parent.Content[i+2:]...)
+				return value, nil
+			}
+		}
+		return nil, fmt.Errorf("key %q not found", token)
+	case yaml.SequenceNode:
+		index, err := sequenceIndex(parent, token, false)
+		if err != nil {
+			return nil, err
+		}
+		value := parent.Content[index]
+		parent.Content = append(parent.Content[:index], parent.Content[index+1:]...)
+		return value, nil
+	default:
+		return nil, fmt.Errorf("cannot remove from scalar")
+	}
+}
+
+// mergeNode applies a strategic-merge fragment to dst in place:
+// mappings are merged key by key, a null value deletes the key, lists of
+// objects with a name field are merged by name (an item with
+// "$patch: delete" removes the match) and any other value is replaced.
+func mergeNode(dst, patch *yaml.Node) *yaml.Node {
+	switch {
+	case dst.Kind == yaml.MappingNode && patch.Kind == yaml.MappingNode:
+		for i := 0; i < len(patch.Content); i += 2 {
+			key, value := patch.Content[i].Value, patch.Content[i+1]
+			if key == patchDirective {
+				continue
+			}
+			found := false
+			for j := 0; j < len(dst.Content); j += 2 {
+				if dst.Content[j].Value != key {
+					continue
+				}
+				found = true
+				if value.Tag == "!!null" {
+					dst.Content = append(dst.Content[:j], dst.Content[j+2:]...)
+				} else {
+					dst.Content[j+1] = mergeNode(dst.Content[j+1], value)
+				}
+				break
+			}
+			if !found && value.Tag != "!!null" {
+				dst.Content = append(dst.Content, copyNode(patch.Content[i]), copyNode(value))
+			}
+		}
+		return dst
+	case dst.Kind == yaml.SequenceNode && patch.Kind == yaml.SequenceNode && namedItems(dst) && namedItems(patch):
+		for _, item := range patch.Content {
+			name := mappingValue(item, "name").Value
+			directive := mappingValue(item, patchDirective)
+			deleteItem := directive != nil && directive.Value == "delete"
+			found := false
+			for j, existing := range dst.Content {
+				if mappingValue(existing, "name").Value != name {
+					continue
+				}
+				found = true
+				if deleteItem {
+					dst.Content = append(dst.Content[:j], dst.Content[j+1:]...)
+				} else {
+					mergeNode(existing, item)
+				}
+				break
+			}
+			if !found && !deleteItem {
+				dst.Content = append(dst.Content, mergeNode(&yaml.Node{Kind: yaml.MappingNode}, item))
+			}
+		}
+		return dst
+	default:
+		return copyNode(patch)
+	}
+}
+
+// namedItems reports whether every item of a sequence is a mapping with a name field.
+func namedItems(seq *yaml.Node) bool {
+	for _, item := range seq.Content {
+		if item.Kind != yaml.MappingNode || mappingValue(item, "name") == nil {
+			return false
+		}
+	}
+	return true
+}
+
+// copyNode returns a deep copy of a YAML node.
+func copyNode(node *yaml.Node) *yaml.Node {
+	if node == nil {
+		return nil
+	}
+	copied := *node
+	copied.Content = make([]*yaml.Node, len(node.Content))
+	for i, child := range node.Content {
+		copied.Content[i] = copyNode(child)
+	}
+	return &copied
+}
+
+// nodesEqual reports whether two nodes decode to the same value.
+func nodesEqual(a, b *yaml.Node) bool {
+	var av, bv interface{}
+	if err := a.Decode(&av); err != nil {
+		return false
+	}
+	if err := b.Decode(&bv); err != nil {
+		return false
+	}
+	return reflect.DeepEqual(av, bv)
+}
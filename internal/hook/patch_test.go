@@ -0,0 +1,233 @@
+package hook
+
+import (
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+func TestProcess_JSONPatchPerHook(t *testing.T) {
+	input := `apiVersion: batch/v1
+kind: Job
+metadata:
+  name: myapp-migration
+  annotations:
+    helm.sh/hook: pre-install,post-upgrade
+    helm.sh/hook-patch-pre-install: |
+      - op: add
+        path: /spec/activeDeadlineSeconds
+        value: 600
+      - op: replace
+        path: /spec/template/spec/containers/0/command
+        value: ["migrate", "--full"]
+spec:
+  template:
+    spec:
+      containers:
+        - name: migrate
+          image: busybox
+          command: ["migrate"]
+`
+
+	output, err := Process([]byte(input))
+	if err != nil {
+		t.Fatalf("Process failed: %v", err)
+	}
+
+	docs := strings.Split(string(output), "---\n")
+	if len(docs) != 2 {
+		t.Fatalf("Expected 2 documents, got %d", len(docs))
+	}
+
+	if !strings.Contains(docs[0], "activeDeadlineSeconds: 600") || !strings.Contains(docs[0], "--full") {
+		t.Errorf("Expected patch on pre-install clone, got:\n%s", docs[0])
+	}
+	if strings.Contains(docs[1], "activeDeadlineSeconds") || strings.Contains(docs[1], "--full") {
+		t.Errorf("Patch should not apply to post-upgrade clone, got:\n%s", docs[1])
+	}
+	if strings.Contains(string(output), "hook-patch") {
+		t.Error("hook-patch annotations should be removed after processing")
+	}
+}
+
+func TestProcess_StrategicMergePatchPerHook(t *testing.T) {
+	input := `apiVersion: batch/v1
+kind: Job
+metadata:
+  name: myapp-migration
+  annotations:
+    helm.sh/hook: pre-install,post-upgrade
+    helm.sh/hook-patch-post-upgrade: |
+      spec:
+        backoffLimit: null
+        template:
+          spec:
+            containers:
+              - name: migrate
+                resources:
+                  limits:
+                    memory: 1Gi
+              - name: sidecar
+                $patch: delete
+spec:
+  backoffLimit: 3
+  template:
+    spec:
+      containers:
+        - name: migrate
+          image: busybox
+        - name: sidecar
+          image: busybox
+`
+
+	output, err := Process([]byte(input))
+	if err != nil {
+		t.Fatalf("Process failed: %v", err)
+	}
+
+	docs := strings.Split(string(output), "---\n")
+	if len(docs) != 2 {
+		t.Fatalf("Expected 2 documents, got %d", len(docs))
+	}
+
+	if !strings.Contains(docs[0], "backoffLimit: 3") || !strings.Contains(docs[0], "sidecar") {
+		t.Errorf("pre-install clone should be unpatched, got:\n%s", docs[0])
+	}
+	if strings.Contains(docs[1], "backoffLimit") {
+		t.Errorf("Expected null to delete backoffLimit, got:\n%s", docs[1])
+	}
+	if strings.Contains(docs[1], "sidecar") {
+		t.Errorf("Expected $patch: delete to remove the sidecar, got:\n%s", docs[1])
+	}
+	if !strings.Contains(docs[1], "memory: 1Gi") || !strings.Contains(docs[1], "image: busybox") {
+		t.Errorf("Expected migrate container to be merged, got:\n%s", docs[1])
+	}
+	// Env vars are injected into the patched containers
+	if !strings.Contains(docs[1], "HELM_HOOK_EVENT") {
+		t.Errorf("Expected env vars on patched clone, got:\n%s", docs[1])
+	}
+}
+
+func TestProcess_InvalidPatch(t *testing.T) {
+	tests := []struct {
+		name       string
+		annotation string
+		want       string
+	}{
+		{"unknown hook", `helm.sh/hook-patch-post-delete: "spec: {}"`, "unknown hook"},
+		{"unknown op", `helm.sh/hook-patch-pre-install: '[{"op": "merge", "path": "/spec"}]'`, "unknown op"},
+		{"relative path", `helm.sh/hook-patch-pre-install: '[{"op": "remove", "path": "spec"}]'`, "must start with /"},
+		{"missing value", `helm.sh/hook-patch-pre-install: '[{"op": "add", "path": "/spec/x"}]'`, "requires a value"},
+		{"missing key", `helm.sh/hook-patch-pre-install: '[{"op": "remove", "path": "/spec/missing"}]'`, "not found"},
+		{"failed test", `helm.sh/hook-patch-pre-install: '[{"op": "test", "path": "/kind", "value": "Pod"}]'`, "test failed"},
+		{"scalar", `helm.sh/hook-patch-pre-install: "replace"`, "expected a JSON Patch list"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			input := `apiVersion: batch/v1
+kind: Job
+metadata:
+  name: myapp-init
+  annotations:
+    helm.sh/hook: pre-install
+    ` + tt.annotation + `
+spec:
+  template:
+    spec:
+      containers:
+        - name: init
+          image: busybox
+`
+
+			_, err := Process([]byte(input))
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Expected error containing %q, got: %v", tt.want, err)
+			}
+		})
+	}
+}
+
+func TestApplyPatchOp(t *testing.T) {
+	doc := `a:
+  b: [1, 2]
+  "c/d": x
+`
+	tests := []struct {
+		name string
+		op   patchOp
+		want string
+	}{
+		{"append", patchOp{Op: "add", Path: "/a/b/-", Value: &yaml.Node{Kind: yaml.ScalarNode, Value: "3"}}, "b: [1, 2, 3]"},
+		{"insert", patchOp{Op: "add", Path: "/a/b/0", Value: &yaml.Node{Kind: yaml.ScalarNode, Value: "0"}}, "b: [0, 1, 2]"},
+		{"escaped key", patchOp{Op: "replace", Path: "/a/c~1d", Value: &yaml.Node{Kind: yaml.ScalarNode, Value: "y"}}, `"c/d": y`},
+		{"move", patchOp{Op: "move", From: "/a/b", Path: "/b"}, "b: [1, 2]\n"},
+		{"copy", patchOp{Op: "copy", From: "/a/b/1", Path: "/a/b/0"}, "b: [2, 1, 2]"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var root yaml.Node
+			if err := yaml.Unmarshal([]byte(doc), &root); err != nil {
+				t.Fatal(err)
+			}
+			if err := applyPatchOp(root.Content[0], tt.op); err != nil {
+				t.Fatalf("applyPatchOp failed: %v", err)
+			}
+			out, err := yaml.Marshal(root.Content[0])
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !strings.Contains(string(out), tt.want) {
+				t.Errorf("Expected %q in:\n%s", tt.want, out)
+			}
+		})
+	}
+}
+
+func TestApplyPatchOp_Replace(t *testing.T) {
+	tests := []struct {
+		name string
+		path string
+		want string
+	}{
+		{"sequence index", "/a/b/0", "b: [9, 2]"},
+		{"mapping key", "/a/e", "e: 9"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var root yaml.Node
+			if err := yaml.Unmarshal([]byte("a:\n  b: [1, 2]\n  e: 3\n"), &root); err != nil {
+				t.Fatal(err)
+			}
+			a := mappingValue(root.Content[0], "a")
+			op := patchOp{Op: "replace", Path: tt.path, Value: &yaml.Node{Kind: yaml.ScalarNode, Value: "9"}}
+			if err := applyPatchOp(root.Content[0], op); err != nil {
+				t.Fatalf("applyPatchOp failed: %v", err)
+			}
+			if len(a.Content) != 4 || len(mappingValue(a, "b").Content) != 2 {
+				t.Errorf("Expected replace to keep the size of a and b, got %d and %d", len(a.Content), len(mappingValue(a, "b").Content))
+			}
+			out, err := yaml.Marshal(root.Content[0])
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !strings.Contains(string(out), tt.want) {
+				t.Errorf("Expected %q in:\n%s", tt.want, out)
+			}
+		})
+	}
+
+	var root yaml.Node
+	if err := yaml.Unmarshal([]byte("a:\n  b: [1, 2]\n"), &root); err != nil {
+		t.Fatal(err)
+	}
+	for _, path := range []string{"/a/b/2", "/a/b/-", "/a/c"} {
+		op := patchOp{Op: "replace", Path: path, Value: &yaml.Node{Kind: yaml.ScalarNode, Value: "9"}}
+		if err := applyPatchOp(root.Content[0], op); err == nil {
+			t.Errorf("Expected replace of missing %s to fail", path)
+		}
+	}
+}
@@ -0,0 +1,261 @@
+package hook
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Plan phases in which Helm acts on the release manifests themselves.
+const (
+	PhaseApply  = "apply"
+	PhaseDelete = "delete"
+)
+
+// operationPhases lists, for each Helm operation, the hook events run
+// before and after the release manifests are applied (or deleted).
+var operationPhases = map[string]struct {
+	pre, post []string
+	main      string
+}{
+	"install":  {pre: []string{"pre-install"}, post: []string{"post-install"}, main: PhaseApply},
+	"upgrade":  {pre: []string{"pre-upgrade"}, post: []string{"post-upgrade"}, main: PhaseApply},
+	"rollback": {pre: []string{"pre-rollback"}, post: []string{"post-rollback"}, main: PhaseApply},
+	"delete":   {pre: []string{"pre-delete"}, post: []string{"post-delete"}, main: PhaseDelete},
+	"test":     {pre: []string{"test"}},
+}
+
+// phaseAliases maps deprecated hook events to the phase Helm runs them in.
+var phaseAliases = map[string]string{
+	"test-success": "test",
+}
+
+// installOrder is Helm's kind ordering, used to break ties between hooks
+// with the same weight and name. Unknown kinds sort last.
+var installOrder = []string{
+	"Namespace",
+	"NetworkPolicy",
+	"ResourceQuota",
+	"LimitRange",
+	"PodSecurityPolicy",
+	"PodDisruptionBudget",
+	"ServiceAccount",
+	"Secret",
+	"SecretList",
+	"ConfigMap",
+	"StorageClass",
+	"PersistentVolume",
+	"PersistentVolumeClaim",
+	"CustomResourceDefinition",
+	"ClusterRole",
+	"ClusterRoleList",
+	"ClusterRoleBinding",
+	"ClusterRoleBindingList",
+	"Role",
+	"RoleList",
+	"RoleBinding",
+	"RoleBindingList",
+	"Service",
+	"DaemonSet",
+	"Pod",
+	"ReplicationController",
+	"ReplicaSet",
+	"Deployment",
+	"HorizontalPodAutoscaler",
+	"StatefulSet",
+	"Job",
+	"CronJob",
+	"IngressClass",
+	"Ingress",
+	"APIService",
+}
+
+// PlannedHook is a single hook resource in an execution plan.
+type PlannedHook struct {
+	Kind   string
+	Name   string
+	Weight int
+}
+
+// PlanPhase is one step of an execution plan: either a hook event or
+// Helm acting on the release manifests (PhaseApply or PhaseDelete).
+type PlanPhase struct {
+	Name  string
+	Hooks []PlannedHook
+	// Manifests is the number of release manifests (PhaseApply and PhaseDelete only).
+	Manifests int
+}
+
+// IsRelease reports whether the phase acts on the release manifests
+// rather than running hooks.
+func (p PlanPhase) IsRelease() bool {
+	return p.Name == PhaseApply || p.Name == PhaseDelete
+}
+
+// ExecutionPlan describes the order in which Helm runs hooks for an operation.
+type ExecutionPlan struct {
+	Operation string
+	Phases    []PlanPhase
+}
+
+// Operations returns the operations supported by Plan.
+func Operations() []string {
+	return []string{"install", "upgrade", "rollback", "delete", "test"}
+}
+
+// Plan returns the hook execution order for an operation using the
+// default configuration.
+func Plan(input []byte, operation string) (*ExecutionPlan, error) {
+	return defaultProcessor.Plan(input, operation)
+}
+
+// Plan processes input the same way Process does and returns the order
+// in which Helm will run the resulting hooks for the given operation.
+// A List document is one release manifest unless Config.FlattenLists is
+// set, in which case its items are planned like top-level documents.
+func (p *Processor) Plan(input []byte, operation string) (*ExecutionPlan, error) {
+	phases, ok := operationPhases[operation]
+	if !ok {
+		return nil, fmt.Errorf("unknown operation %q (valid: %s)", operation, strings.Join(Operations(), ", "))
+	}
+
+	output, err := p.Process(input)
+	if err != nil {
+		return nil, err
+	}
+
+	hooksByEvent := make(map[string][]PlannedHook)
+	manifests := 0
+
+	// addResource records a hook or release manifest, descending into
+	// the items of List documents when lists are flattened
+	var addResource func(node *yaml.Node) error
+	addResource = func(node *yaml.Node) error {
+		res, err := parseResource(node)
+		if err != nil {
+			return err
+		}
+		if res.Kind == "" {
+			return nil
+		}
+		if items := listItems(node, res); items != nil && p.cfg.FlattenLists {
+			for _, item := range items.Content {
+				if err := addResource(item); err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+
+		hookValue, hasHook := res.Annotations[annotationHook]
+		if !hasHook {
+			manifests++
+			return nil
+		}
+
+		events := parseHookEvents(hookValue)
+		weights, err := p.parseWeights(res.Annotations, events)
+		if err != nil {
+			return fmt.Errorf("resource %q: %w", res.Name, err)
+		}
+
+		for _, event := range events {
+			phase := event
+			if alias, ok := phaseAliases[event]; ok {
+				phase = alias
+			}
+			hooksByEvent[phase] = append(hooksByEvent[phase], PlannedHook{
+				Kind:   res.Kind,
+				Name:   res.Name,
+				Weight: weights[event],
+			})
+		}
+		return nil
+	}
+
+	decoder := yaml.NewDecoder(bytes.NewReader(output))
+	for {
+		var node yaml.Node
+		if err := decoder.Decode(&node); err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			return nil, fmt.Errorf("parsing YAML: %w", err)
+		}
+		if err := addResource(&node); err != nil {
+			return nil, err
+		}
+	}
+
+	plan := &ExecutionPlan{Operation: operation}
+	addPhases := func(events []string) {
+		for _, event := range events {
+			hooks := hooksByEvent[event]
+			sortHooks(hooks)
+			plan.Phases = append(plan.Phases, PlanPhase{Name: event, Hooks: hooks})
+		}
+	}
+
+	addPhases(phases.pre)
+	if phases.main != "" {
+		plan.Phases = append(plan.Phases, PlanPhase{Name: phases.main, Manifests: manifests})
+	}
+	addPhases(phases.post)
+
+	return plan, nil
+}
+
+// sortHooks orders hooks the way Helm executes them:
+// by weight, then name, then kind install order.
+func sortHooks(hooks []PlannedHook) {
+	sort.SliceStable(hooks, func(i, j int) bool {
+		if hooks[i].Weight != hooks[j].Weight {
+			return hooks[i].Weight < hooks[j].Weight
+		}
+		if hooks[i].Name != hooks[j].Name {
+			return hooks[i].Name < hooks[j].Name
+		}
+		return kindRank(hooks[i].Kind) < kindRank(hooks[j].Kind)
+	})
+}
+
+// kindRank returns the position of kind in Helm's install order.
+func kindRank(kind string) int {
+	for i, k := range installOrder {
+		if k == kind {
+			return i
+		}
+	}
+	return len(installOrder)
+}
+
+// WriteText writes a human-readable execution plan to w.
+func (p *ExecutionPlan) WriteText(w io.Writer) error {
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "Operation: %s\n", p.Operation)
+
+	for i, phase := range p.Phases {
+		fmt.Fprintln(&buf)
+		if phase.IsRelease() {
+			fmt.Fprintf(&buf, "%d. %s (%d release manifests)\n", i+1, phase.Name, phase.Manifests)
+			continue
+		}
+
+		fmt.Fprintf(&buf, "%d. %s\n", i+1, phase.Name)
+		if len(phase.Hooks) == 0 {
+			fmt.Fprintln(&buf, "     (no hooks)")
+			continue
+		}
+		for _, h := range phase.Hooks {
+			fmt.Fprintf(&buf, "   %6d  %s/%s\n", h.Weight, h.Kind, h.Name)
+		}
+	}
+
+	_, err := w.Write(buf.Bytes())
+	return err
+}
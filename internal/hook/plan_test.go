@@ -0,0 +1,164 @@
+package hook
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+const planInput = `apiVersion: batch/v1
+kind: Job
+metadata:
+  name: myapp-migration
+  annotations:
+    helm.sh/hook: pre-install,pre-upgrade,post-upgrade
+    helm.sh/hook-weights: "-100,-50,200"
+---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: myapp-seed
+  annotations:
+    helm.sh/hook: pre-upgrade
+    helm.sh/hook-weight: "-50"
+---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: myapp-config
+---
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: myapp
+`
+
+func TestPlan_Upgrade(t *testing.T) {
+	plan, err := Plan([]byte(planInput), "upgrade")
+	if err != nil {
+		t.Fatalf("Plan failed: %v", err)
+	}
+
+	if len(plan.Phases) != 3 {
+		t.Fatalf("Expected 3 phases, got %d", len(plan.Phases))
+	}
+
+	pre := plan.Phases[0]
+	if pre.Name != "pre-upgrade" || len(pre.Hooks) != 2 {
+		t.Fatalf("Expected 2 pre-upgrade hooks, got %+v", pre)
+	}
+	// Same weight: ordered by name
+	if pre.Hooks[0].Name != "myapp-migration-pre-upgrade" || pre.Hooks[1].Name != "myapp-seed" {
+		t.Errorf("Unexpected pre-upgrade order: %+v", pre.Hooks)
+	}
+
+	apply := plan.Phases[1]
+	if apply.Name != PhaseApply || apply.Manifests != 2 {
+		t.Errorf("Expected apply phase with 2 manifests, got %+v", apply)
+	}
+
+	post := plan.Phases[2]
+	if post.Name != "post-upgrade" || len(post.Hooks) != 1 || post.Hooks[0].Weight != 200 {
+		t.Errorf("Unexpected post-upgrade phase: %+v", post)
+	}
+}
+
+func TestPlan_UnknownOperation(t *testing.T) {
+	_, err := Plan([]byte(planInput), "reinstall")
+	if err == nil || !strings.Contains(err.Error(), "unknown operation") {
+		t.Errorf("Expected unknown operation error, got: %v", err)
+	}
+}
+
+func TestSortHooks_KindTieBreak(t *testing.T) {
+	hooks := []PlannedHook{
+		{Kind: "Job", Name: "same", Weight: 0},
+		{Kind: "ConfigMap", Name: "same", Weight: 0},
+		{Kind: "Job", Name: "early", Weight: -1},
+	}
+	sortHooks(hooks)
+
+	order := []string{hooks[0].Kind + "/" + hooks[0].Name, hooks[1].Kind + "/" + hooks[1].Name, hooks[2].Kind + "/" + hooks[2].Name}
+	expected := []string{"Job/early", "ConfigMap/same", "Job/same"}
+	for i := range expected {
+		if order[i] != expected[i] {
+			t.Errorf("Expected order %v, got %v", expected, order)
+			break
+		}
+	}
+}
+
+func TestExecutionPlan_WriteText(t *testing.T) {
+	plan, err := Plan([]byte(planInput), "install")
+	if err != nil {
+		t.Fatalf("Plan failed: %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := plan.WriteText(&buf); err != nil {
+		t.Fatalf("WriteText failed: %v", err)
+	}
+
+	text := buf.String()
+	if !strings.Contains(text, "1. pre-install") || !strings.Contains(text, "2. apply (2 release manifests)") {
+		t.Errorf("Unexpected plan text:\n%s", text)
+	}
+	if !strings.Contains(text, "(no hooks)") {
+		t.Errorf("Expected empty post-install phase, got:\n%s", text)
+	}
+}
+
+func TestPlan_ListItems(t *testing.T) {
+	input := `apiVersion: v1
+kind: List
+items:
+  - apiVersion: batch/v1
+    kind: Job
+    metadata:
+      name: migrate
+      annotations:
+        helm.sh/hook: pre-install
+        helm.sh/hook-weight: "-5"
+  - apiVersion: v1
+    kind: ConfigMap
+    metadata:
+      name: config
+`
+
+	// Helm only detects hooks on top-level documents, so the List is
+	// one release manifest
+	plan, err := Plan([]byte(input), "install")
+	if err != nil {
+		t.Fatalf("Plan failed: %v", err)
+	}
+	if pre := plan.Phases[0]; len(pre.Hooks) != 0 {
+		t.Errorf("Expected no hooks without flattening, got %+v", pre)
+	}
+	if apply := plan.Phases[1]; apply.Manifests != 1 {
+		t.Errorf("Expected the List as 1 release manifest, got %+v", apply)
+	}
+
+	cfg := DefaultConfig()
+	cfg.FlattenLists = true
+	plan, err = NewProcessor(cfg).Plan([]byte(input), "install")
+	if err != nil {
+		t.Fatalf("Plan failed: %v", err)
+	}
+
+	pre := plan.Phases[0]
+	if len(pre.Hooks) != 1 || pre.Hooks[0] != (PlannedHook{Kind: "Job", Name: "migrate", Weight: -5}) {
+		t.Errorf("Expected the Job item as a pre-install hook, got %+v", pre)
+	}
+	// The List itself is not a release manifest, only its ConfigMap item
+	if apply := plan.Phases[1]; apply.Manifests != 1 {
+		t.Errorf("Expected 1 release manifest, got %+v", apply)
+	}
+
+	var buf bytes.Buffer
+	if err := plan.WriteMermaid(&buf); err != nil {
+		t.Fatalf("WriteMermaid failed: %v", err)
+	}
+	if !strings.Contains(buf.String(), "Job/migrate") {
+		t.Errorf("Expected the Job item in the graph:\n%s", buf.String())
+	}
+}
@@ -0,0 +1,117 @@
+package hook
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Valid Helm hook delete policies
+var validDeletePolicies = map[string]bool{
+	"before-hook-creation": true,
+	"hook-succeeded":       true,
+	"hook-failed":          true,
+}
+
+// deletePolicySeparator separates multiple policies for a single hook event.
+// A comma cannot be used because it already separates hook events.
+const deletePolicySeparator = "|"
+
+// parseDeletePolicies parses helm.sh/hook-delete-policies in two formats:
+// 1. Explicit: "pre-install=before-hook-creation,post-upgrade=hook-succeeded|hook-failed"
+// 2. Positional: "before-hook-creation,hook-succeeded|hook-failed" (matches order of hooks)
+// Hooks without a policy are omitted from the result and keep any inherited
+// helm.sh/hook-delete-policy.
+func parseDeletePolicies(value string, hooks []string) (map[string]string, error) {
+	policies := make(map[string]string)
+
+	parts := strings.Split(value, ",")
+
+	isExplicit := false
+	for _, part := range parts {
+		if strings.Contains(part, "=") {
+			isExplicit = true
+			break
+		}
+	}
+
+	if isExplicit {
+		// Explicit format: "pre-install=before-hook-creation,post-upgrade=hook-succeeded"
+		for _, pair := range parts {
+			pair = strings.TrimSpace(pair)
+			if pair == "" {
+				continue
+			}
+
+			kv := strings.SplitN(pair, "=", 2)
+			if len(kv) != 2 {
+				return nil, fmt.Errorf("invalid delete policy mapping %q, expected format hook=policy", pair)
+			}
+
+			hookName := strings.TrimSpace(kv[0])
+
+			found := false
+			for _, h := range hooks {
+				if h == hookName {
+					found = true
+					break
+				}
+			}
+			if !found {
+				return nil, fmt.Errorf("delete policy specified for unknown hook %q", hookName)
+			}
+
+			policy, err := parsePolicyList(kv[1])
+			if err != nil {
+				return nil, fmt.Errorf("invalid delete policy for hook %q: %w", hookName, err)
+			}
+			policies[hookName] = policy
+		}
+	} else {
+		// Positional format: "before-hook-creation,hook-succeeded|hook-failed"
+		if len(parts) != len(hooks) {
+			return nil, fmt.Errorf("positional delete policies count (%d) doesn't match hook count (%d)", len(parts), len(hooks))
+		}
+
+		for i, part := range parts {
+			if strings.TrimSpace(part) == "" {
+				continue
+			}
+
+			policy, err := parsePolicyList(part)
+			if err != nil {
+				return nil, fmt.Errorf("invalid delete policy at position %d: %w", i+1, err)
+			}
+			policies[hooks[i]] = policy
+		}
+	}
+
+	return policies, nil
+}
+
+// parsePolicyList validates a "|"-separated policy list and returns it in
+// the comma-separated form Helm expects in helm.sh/hook-delete-policy.
+func parsePolicyList(value string) (string, error) {
+	var policies []string
+	seen := make(map[string]bool)
+
+	for _, p := range strings.Split(value, deletePolicySeparator) {
+		p = strings.TrimSpace(p)
+		if p == "" {
+			continue
+		}
+		if !validDeletePolicies[p] {
+			return "", fmt.Errorf("unknown policy %q", p)
+		}
+		if seen[p] {
+			continue
+		}
+		seen[p] = true
+		policies = append(policies, p)
+	}
+
+	if len(policies) == 0 {
+		return "", fmt.Errorf("empty policy")
+	}
+
+	return strings.Join(policies, ","), nil
+}
@@ -5,7 +5,10 @@ package hook
 
 import (
 	"bytes"
+	"context"
+	"errors"
 	"fmt"
+	"io"
 	"strconv"
 	"strings"
 
@@ -14,12 +17,25 @@ import (
 
 const (
 	// Helm hook annotations
-	annotationHook         = "helm.sh/hook"
-	annotationHookWeight   = "helm.sh/hook-weight"
-	annotationHookWeights  = "helm.sh/hook-weights"
-	annotationHookEnv      = "helm.sh/hook-env"
-	annotationHookNameSuffix = "helm.sh/hook-name-suffix"
-	annotationHookDeletePolicy = "helm.sh/hook-delete-policy"
+	annotationHook                     = "helm.sh/hook"
+	annotationHookWeight               = "helm.sh/hook-weight"
+	annotationHookWeights              = "helm.sh/hook-weights"
+	annotationHookEnv                  = "helm.sh/hook-env"
+	annotationHookNameSuffix           = "helm.sh/hook-name-suffix"
+	annotationHookNameTemplate         = "helm.sh/hook-name-template"
+	annotationHookDeletePolicy         = "helm.sh/hook-delete-policy"
+	annotationHookDeletePolicies       = "helm.sh/hook-delete-policies"
+	annotationHookEnvVars              = "helm.sh/hook-env-vars"
+	annotationHookEnvNames             = "helm.sh/hook-env-names"
+	annotationHookEnvPrefix            = "helm.sh/hook-env-prefix"
+	annotationHookEnvDownward          = "helm.sh/hook-env-downward"
+	annotationHookEnvPaths             = "helm.sh/hook-env-paths"
+	annotationHookEnvMode              = "helm.sh/hook-env-mode"
+	annotationHookEnvContainers        = "helm.sh/hook-env-containers"
+	annotationHookEnvExcludeContainers = "helm.sh/hook-env-exclude-containers"
+	// Prefix of the per-event helm.sh/hook-patch-<event> annotations
+	annotationHookPatch     = "helm.sh/hook-patch"
+	annotationHookDependsOn = "helm.sh/hook-depends-on"
 
 	// Default weight when not specified
 	defaultWeight = 0
@@ -27,143 +43,304 @@ const (
 
 // Resource represents a Kubernetes resource with typed access to common fields.
 type Resource struct {
-	node     *yaml.Node
-	Kind     string
-	Name     string
+	node        *yaml.Node
+	APIVersion  string
+	Kind        string
+	Name        string
 	Annotations map[string]string
 }
 
+// Process takes raw YAML input and returns enhanced YAML output
+// using the default configuration.
+func Process(input []byte) ([]byte, error) {
+	return defaultProcessor.Process(input)
+}
+
 // Process takes raw YAML input and returns enhanced YAML output.
 // It parses multi-document YAML, processes hooks, and reconstructs the output.
-func Process(input []byte) ([]byte, error) {
-	decoder := yaml.NewDecoder(bytes.NewReader(input))
-	var outputDocs [][]byte
+func (p *Processor) Process(input []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := p.ProcessStream(context.Background(), bytes.NewReader(input), &buf); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
 
-	for {
-		var node yaml.Node
-		err := decoder.Decode(&node)
+// ProcessStream enhances YAML read from r and writes it to w using the
+// default configuration.
+func ProcessStream(ctx context.Context, r io.Reader, w io.Writer) error {
+	return defaultProcessor.ProcessStream(ctx, r, w)
+}
+
+// ProcessStream decodes, transforms and encodes one document at a time,
+// so memory use is bounded by the largest document rather than the whole
+// stream. Documents are joined with YAML document separators. Processing
+// stops when ctx is cancelled; output already written is not retracted.
+//
+// Documents that need no changes are copied byte-for-byte, preserving
+// comments, key order and scalar styles. Changed documents are re-encoded
+// from their node tree with the indentation detected in the input.
+//
+// Documents declaring helm.sh/hook-depends-on are held back until the end
+// of the stream, when the weights of all hooks are known. The documents
+// after the first of them are held back too, so the output keeps the
+// order of the input; memory use then grows with the rest of the stream.
+//
+// Every output is also checked against the rest of the release, e.g. for
+// duplicate objects; the first problem found stops processing.
+func (p *Processor) ProcessStream(ctx context.Context, r io.Reader, w io.Writer) error {
+	if err := checkMaxNameLength(p.cfg.nameLength()); err != nil {
+		return err
+	}
+
+	p = p.forStream()
+	reader := newDocumentReader(r)
+	deps := p.newDependencyResolver()
+	release := p.newReleaseValidator()
+	first := true
+
+	writeDoc := func(doc []byte) error {
+		if !first {
+			if _, err := io.WriteString(w, "---\n"); err != nil {
+				return fmt.Errorf("writing output: %w", err)
+			}
+		}
+		first = false
+		if _, err := w.Write(doc); err != nil {
+			return fmt.Errorf("writing output: %w", err)
+		}
+		if !bytes.HasSuffix(doc, []byte("\n")) {
+			if _, err := io.WriteString(w, "\n"); err != nil {
+				return fmt.Errorf("writing output: %w", err)
+			}
+		}
+		return nil
+	}
+
+	emit := func(index int, node *yaml.Node, raw []byte) error {
+		p.recorder.at(index)
+		processed, err := p.processDocument(node)
 		if err != nil {
-			if err.Error() == "EOF" {
-				break
+			return err
+		}
+
+		outputs := processed
+		if outputs == nil {
+			outputs = []*yaml.Node{node}
+		}
+		if problems := release.add(index, outputs); len(problems) > 0 {
+			return errors.New(problems[0].String())
+		}
+		if p.observe != nil {
+			p.observe(index, raw, processed)
+		}
+
+		// Unchanged documents are copied verbatim
+		if processed == nil {
+			return writeDoc(raw)
+		}
+
+		indent := detectIndent(raw)
+		for _, out := range processed {
+			doc, err := marshalNodeIndent(out, indent)
+			if err != nil {
+				return err
+			}
+			if err := writeDoc(doc); err != nil {
+				return err
 			}
-			return nil, fmt.Errorf("parsing YAML: %w", err)
 		}
+		return nil
+	}
+
+	type deferredDoc struct {
+		index int
+		node  *yaml.Node
+		raw   []byte
+	}
+	var deferred []deferredDoc
 
-		// Process this document
-		processed, err := processDocument(&node)
+	for index := 1; ; index++ {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		raw, err := reader.next()
 		if err != nil {
-			return nil, err
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			return fmt.Errorf("reading input: %w", err)
+		}
+
+		var node yaml.Node
+		if err := yaml.Unmarshal(raw, &node); err != nil {
+			return fmt.Errorf("parsing YAML document %d: %w", index, err)
 		}
 
-		outputDocs = append(outputDocs, processed...)
+		// Hold back documents whose weights depend on later documents,
+		// and everything after them to keep the input order
+		if deps.add(&node, index) || len(deferred) > 0 {
+			deferred = append(deferred, deferredDoc{index: index, node: &node, raw: raw})
+			continue
+		}
+
+		if err := emit(index, &node, raw); err != nil {
+			return err
+		}
 	}
 
-	// Combine all documents with YAML document separators
-	return combineDocuments(outputDocs), nil
+	if err := deps.resolve(); err != nil {
+		return err
+	}
+	for _, doc := range deferred {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := emit(doc.index, doc.node, doc.raw); err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
-// processDocument handles a single YAML document.
-// Returns one or more YAML byte slices (splitting produces multiple).
-func processDocument(node *yaml.Node) ([][]byte, error) {
-	// Extract resource metadata
+// processDocument handles a single YAML document and applies the output profile.
+// Returns one or more output nodes (splitting produces multiple), or nil
+// when the document is left unchanged and can be copied verbatim.
+func (p *Processor) processDocument(node *yaml.Node) ([]*yaml.Node, error) {
+	// Descend into List documents
 	res, err := parseResource(node)
 	if err != nil {
 		return nil, err
 	}
+	p.recorder.begin(res)
+	defer p.recorder.end()
 
-	// Check for hook annotations
-	hookValue, hasHook := res.Annotations[annotationHook]
-	weightsValue, hasWeights := res.Annotations[annotationHookWeights]
-	weightValue, hasWeight := res.Annotations[annotationHookWeight]
-
-	// Not a hook resource at all - pass through unchanged
-	if !hasHook && !hasWeights {
-		out, err := marshalNode(node)
-		if err != nil {
-			return nil, err
-		}
-		return [][]byte{out}, nil
+	if items := listItems(node, res); items != nil {
+		return p.processList(node, res, items)
 	}
 
-	var hooks []string
+	processed, err := p.transformDocument(node)
+	if err != nil {
+		return nil, err
+	}
 
-	// Case 1: hook-weights without hook annotation - auto-generate hooks
-	if hasWeights && !hasHook {
-		hooks, err = extractHooksFromWeights(weightsValue)
+	switch p.cfg.Profile {
+	case "", ProfileHelm:
+		return processed, nil
+	case ProfileArgoCD:
+		converted, err := p.convertToArgoCD(node, processed)
 		if err != nil {
-			return nil, fmt.Errorf("resource %q: %w", res.Name, err)
+			return nil, err
 		}
-	} else {
-		// Parse hook events from annotation
-		hooks = parseHookEvents(hookValue)
-		if len(hooks) == 0 {
-			return nil, fmt.Errorf("resource %q has empty %s annotation", res.Name, annotationHook)
+		if processed == nil && converted != nil {
+			// An unchanged hook still has its annotations translated
+			weight, _ := strconv.Atoi(strings.TrimSpace(res.Annotations[annotationHookWeight]))
+			p.recorder.setAction(ActionEnhanced)
+			p.recorder.addOutput(OutputReport{Name: res.Name, Event: res.Annotations[annotationHook], Weight: weight})
 		}
+		p.recorder.keepOutputs(converted)
+		return converted, nil
+	default:
+		return nil, fmt.Errorf("unknown profile %q", p.cfg.Profile)
 	}
+}
 
-	// Validate hooks
-	if err := validateHooks(hooks, res.Name); err != nil {
+// transformDocument splits and enhances a single hook document.
+// Returns nil when the document is left unchanged.
+func (p *Processor) transformDocument(node *yaml.Node) ([]*yaml.Node, error) {
+	// Extract resource metadata
+	res, err := parseResource(node)
+	if err != nil {
 		return nil, err
 	}
 
+	// Check for hook annotations
+	_, hasHook := res.Annotations[annotationHook]
+	_, hasWeights := res.Annotations[p.cfg.annotationKey(annotationHookWeights)]
+	weightValue, hasWeight := res.Annotations[annotationHookWeight]
+	_, hasPolicies := res.Annotations[p.cfg.annotationKey(annotationHookDeletePolicies)]
+	_, hasDependsOn := res.Annotations[p.cfg.annotationKey(annotationHookDependsOn)]
+
+	// Not a hook resource at all, or a kind we were told to skip - pass through unchanged
+	if (!hasHook && !hasWeights) || !p.cfg.kindSelected(res.Kind) {
+		return nil, nil
+	}
+
+	// Parse and validate hooks, weights, policies, env vars and patches
+	hc, problems := p.parseHookConfig(res)
+	if len(problems) > 0 {
+		return nil, problems.err(res.Name)
+	}
+	hooks, names := hc.hooks, hc.names
+
 	// Check for passthrough case: single hook with single weight, no hook-weights
-	if len(hooks) == 1 && !hasWeights {
+	if len(hooks) == 1 && !hasWeights && !hasPolicies && !hasDependsOn && !p.hasHookEnvVars(res) && !p.hasHookPatches(res) {
 		// Single hook - check if we need to modify at all
 		if !hasWeight || isSingleValidWeight(weightValue) {
 			// Already valid, just add env vars if enabled
-			envEnabled := true
-			if envVal, ok := res.Annotations[annotationHookEnv]; ok {
-				envEnabled = strings.ToLower(envVal) == "true"
-			}
-			if envEnabled {
-				weight := 0
-				if hasWeight {
-					weight, _ = strconv.Atoi(strings.TrimSpace(weightValue))
-				}
-				if err := injectEnvVarsOnly(node, hooks[0], weight); err != nil {
-					return nil, err
-				}
+			if !p.envEnabled(res) {
+				return nil, nil
 			}
-			out, err := marshalNode(node)
+			spec := hookSpec{event: hooks[0], weight: hc.weights[hooks[0]], names: names}
+			p.recorder.setAction(ActionEnhanced)
+			configMap, err := p.injectEnvVarsOnly(node, spec, res.Name)
 			if err != nil {
 				return nil, err
 			}
-			return [][]byte{out}, nil
+			p.warnUnmatchedContainers(res, names)
+			return withConfigMap(configMap, node), nil
 		}
 	}
 
-	// Parse weights for each hook (with validation)
-	weights, err := parseWeights(res.Annotations, hooks)
-	if err != nil {
-		return nil, fmt.Errorf("resource %q: %w", res.Name, err)
+	specs := make([]hookSpec, len(hooks))
+	for i, h := range hooks {
+		specs[i] = hookSpec{event: h, weight: hc.weights[h], deletePolicy: hc.policies[h], names: names, env: hc.envs[h], patch: hc.patches[h]}
 	}
 
 	// Check if env injection is enabled (default: true)
-	envEnabled := true
-	if envVal, ok := res.Annotations[annotationHookEnv]; ok {
-		envEnabled = strings.ToLower(envVal) == "true"
+	envEnabled := p.envEnabled(res)
+	if !envEnabled && hasCustomEnv(specs) {
+		p.warn(res, "custom env vars are ignored because %s is false", p.cfg.annotationKey(annotationHookEnv))
 	}
 
 	// Check if name suffix is enabled (default: true for multi-hooks)
-	nameSuffixEnabled := true
-	if suffixVal, ok := res.Annotations[annotationHookNameSuffix]; ok {
+	nameSuffixEnabled := p.cfg.NameSuffixDefault
+	if suffixVal, ok := res.Annotations[p.cfg.annotationKey(annotationHookNameSuffix)]; ok {
 		nameSuffixEnabled = strings.ToLower(suffixVal) != "false"
 	}
 
 	// Single hook with processing needed
 	if len(hooks) == 1 {
-		if err := enhanceResource(node, hooks[0], weights[hooks[0]], envEnabled); err != nil {
-			return nil, err
-		}
-		out, err := marshalNode(node)
+		p.recorder.setAction(ActionEnhanced)
+		configMap, err := p.enhanceResource(node, specs[0], envEnabled)
 		if err != nil {
 			return nil, err
 		}
-		return [][]byte{out}, nil
+		if envEnabled {
+			p.warnUnmatchedContainers(res, names)
+		}
+		return withConfigMap(configMap, node), nil
 	}
 
 	// Multiple hooks: split into separate resources
-	return splitResource(node, res, hooks, weights, envEnabled, nameSuffixEnabled)
+	p.recorder.setAction(ActionSplit)
+	results, err := p.splitResource(node, res, specs, envEnabled, nameSuffixEnabled)
+	if err != nil {
+		return nil, err
+	}
+	if envEnabled {
+		p.warnUnmatchedContainers(res, names)
+	}
+	return results, nil
+}
+
+// envEnabled reports whether env injection is enabled for a resource.
+func (p *Processor) envEnabled(res *Resource) bool {
+	if envVal, ok := res.Annotations[p.cfg.annotationKey(annotationHookEnv)]; ok {
+		return strings.ToLower(envVal) == "true"
+	}
+	return p.cfg.EnvDefault
 }
 
 // extractHooksFromWeights parses hook names from helm.sh/hook-weights
@@ -199,13 +376,28 @@ func isSingleValidWeight(value string) bool {
 	return err == nil
 }
 
-// injectEnvVarsOnly adds env vars without modifying annotations
-func injectEnvVarsOnly(node *yaml.Node, hookEvent string, weight int) error {
+// injectEnvVarsOnly adds env vars without modifying annotations.
+// It returns the generated ConfigMap in envFrom mode.
+func (p *Processor) injectEnvVarsOnly(node *yaml.Node, spec hookSpec, name string) (*yaml.Node, error) {
 	content := node
 	if node.Kind == yaml.DocumentNode && len(node.Content) > 0 {
 		content = node.Content[0]
 	}
-	return injectEnvVars(content, hookEvent, weight)
+	containers, configMap, err := p.injectEnv(content, spec, name)
+	if err != nil {
+		return nil, err
+	}
+	p.recorder.addOutput(OutputReport{Name: name, Event: spec.event, Weight: spec.weight, EnvContainers: containers})
+	return configMap, nil
+}
+
+// withConfigMap returns the outputs of a hook resource, preceded by its
+// generated env ConfigMap if any.
+func withConfigMap(configMap, node *yaml.Node) []*yaml.Node {
+	if configMap == nil {
+		return []*yaml.Node{node}
+	}
+	return []*yaml.Node{configMap, node}
 }
 
 // parseResource extracts metadata from a YAML node.
@@ -231,6 +423,8 @@ func parseResource(node *yaml.Node) (*Resource, error) {
 		value := content.Content[i+1]
 
 		switch key.Value {
+		case "apiVersion":
+			res.APIVersion = value.Value
 		case "kind":
 			res.Kind = value.Value
 		case "metadata":
@@ -284,7 +478,7 @@ func parseHookEvents(value string) []string {
 
 // parseWeights determines the weight for each hook event.
 // Precedence: helm.sh/hook-weights > comma-separated helm.sh/hook-weight > single weight > default (0)
-func parseWeights(annotations map[string]string, hooks []string) (map[string]int, error) {
+func (p *Processor) parseWeights(annotations map[string]string, hooks []string) (map[string]int, error) {
 	weights := make(map[string]int)
 
 	// Initialize all hooks with default weight
@@ -293,7 +487,7 @@ func parseWeights(annotations map[string]string, hooks []string) (map[string]int
 	}
 
 	// Check for explicit hook-weights mapping (highest priority)
-	if weightsVal, ok := annotations[annotationHookWeights]; ok {
+	if weightsVal, ok := annotations[p.cfg.annotationKey(annotationHookWeights)]; ok {
 		return parseExplicitWeights(weightsVal, hooks)
 	}
 
@@ -412,33 +606,69 @@ func parseExplicitWeights(value string, hooks []string) (map[string]int, error)
 }
 
 // enhanceResource modifies a resource node to add hook enhancements.
-func enhanceResource(node *yaml.Node, hookEvent string, weight int, envEnabled bool) error {
+// A non-empty spec.deletePolicy replaces helm.sh/hook-delete-policy.
+// It returns the generated ConfigMap in envFrom mode.
+func (p *Processor) enhanceResource(node *yaml.Node, spec hookSpec, envEnabled bool) (*yaml.Node, error) {
 	content := node
 	if node.Kind == yaml.DocumentNode && len(node.Content) > 0 {
 		content = node.Content[0]
 	}
 
 	// Update the hook-weight annotation
-	if err := setAnnotation(content, annotationHookWeight, strconv.Itoa(weight)); err != nil {
-		return err
+	if err := setAnnotation(content, annotationHookWeight, strconv.Itoa(spec.weight)); err != nil {
+		return nil, err
 	}
 
 	// Set hook to single event
-	if err := setAnnotation(content, annotationHook, hookEvent); err != nil {
-		return err
+	if err := setAnnotation(content, annotationHook, spec.event); err != nil {
+		return nil, err
 	}
 
-	// Remove hook-weights as it's been processed
-	removeAnnotation(content, annotationHookWeights)
+	// Set per-hook delete policy
+	if spec.deletePolicy != "" {
+		if err := setAnnotation(content, annotationHookDeletePolicy, spec.deletePolicy); err != nil {
+			return nil, err
+		}
+	}
+
+	// Remove helm-hooks' own annotations as they've been processed
+	p.removeProcessedAnnotations(content)
+
+	// Apply the per-hook patch before env injection so patched containers get env vars
+	if spec.patch != nil {
+		if err := spec.patch.apply(content); err != nil {
+			return nil, fmt.Errorf("applying %s: %w", p.eventPatchKey(spec.event), err)
+		}
+	}
+
+	name := ""
+	if v := mappingValue(mappingValue(content, "metadata"), "name"); v != nil {
+		name = v.Value
+	}
 
 	// Inject environment variables if enabled
+	var containers []string
+	var configMap *yaml.Node
 	if envEnabled {
-		if err := injectEnvVars(content, hookEvent, weight); err != nil {
-			return err
+		var err error
+		if containers, configMap, err = p.injectEnv(content, spec, name); err != nil {
+			return nil, err
 		}
 	}
 
-	return nil
+	p.recorder.addOutput(OutputReport{Name: name, Event: spec.event, Weight: spec.weight, EnvContainers: containers})
+
+	return configMap, nil
+}
+
+// removeProcessedAnnotations removes the helm-hooks annotations that are
+// consumed during processing and have no meaning to Helm.
+func (p *Processor) removeProcessedAnnotations(node *yaml.Node) {
+	removeAnnotation(node, p.cfg.annotationKey(annotationHookWeights))
+	removeAnnotation(node, p.cfg.annotationKey(annotationHookDeletePolicies))
+	removeAnnotation(node, p.cfg.annotationKey(annotationHookDependsOn))
+	p.removeEnvVarAnnotations(node)
+	p.removePatchAnnotations(node)
 }
 
 // setAnnotation sets an annotation value on a resource.
@@ -547,24 +777,58 @@ func removeAnnotationKey(annotations *yaml.Node, key string) {
 	}
 }
 
-// injectEnvVars adds HELM_HOOK_EVENT and HELM_HOOK_WEIGHT to all containers.
-func injectEnvVars(node *yaml.Node, hookEvent string, weight int) error {
+// injectEnvVars adds the injector's variables to all containers.
+func (inj *envInjector) injectEnvVars(node *yaml.Node) error {
 	if node.Kind != yaml.MappingNode {
 		return nil
 	}
+	if len(inj.paths) > 0 {
+		inj.injectEnvInPaths(node)
+		return nil
+	}
 
 	// Find spec
 	for i := 0; i < len(node.Content); i += 2 {
 		if node.Content[i].Value == "spec" {
-			return injectEnvInSpec(node.Content[i+1], hookEvent, weight)
+			spec := node.Content[i+1]
+			if mappingValue(spec, "containers") != nil {
+				// A Pod carries its own annotations
+				inj.annotatePod(node)
+			}
+			return inj.injectEnvInSpec(spec)
 		}
 	}
 
 	return nil
 }
 
+// annotatePod adds the injector's pod annotations to the metadata of a
+// Pod or pod template, creating it if needed.
+func (inj *envInjector) annotatePod(pod *yaml.Node) {
+	if len(inj.podAnnotations) == 0 || pod.Kind != yaml.MappingNode {
+		return
+	}
+	metadata := mappingValue(pod, "metadata")
+	if metadata == nil {
+		// Keep metadata ahead of spec, as in hand-written templates
+		metadata = &yaml.Node{Kind: yaml.MappingNode}
+		pod.Content = append([]*yaml.Node{{Kind: yaml.ScalarNode, Value: "metadata"}, metadata}, pod.Content...)
+	} else if metadata.Kind != yaml.MappingNode {
+		metadata = &yaml.Node{Kind: yaml.MappingNode}
+		setMappingValue(pod, "metadata", metadata)
+	}
+	annotations := mappingValue(metadata, "annotations")
+	if annotations == nil || annotations.Kind != yaml.MappingNode {
+		annotations = &yaml.Node{Kind: yaml.MappingNode}
+		setMappingValue(metadata, "annotations", annotations)
+	}
+	for _, a := range inj.podAnnotations {
+		setAnnotationValue(annotations, a.Name, a.Value)
+	}
+}
+
 // injectEnvInSpec handles environment injection in the spec.
-func injectEnvInSpec(spec *yaml.Node, hookEvent string, weight int) error {
+func (inj *envInjector) injectEnvInSpec(spec *yaml.Node) error {
 	if spec.Kind != yaml.MappingNode {
 		return nil
 	}
@@ -575,13 +839,17 @@ func injectEnvInSpec(spec *yaml.Node, hookEvent string, weight int) error {
 		switch key {
 		case "template":
 			// Pod template spec
-			return injectEnvInPodTemplate(spec.Content[i+1], hookEvent, weight)
+			return inj.injectEnvInPodTemplate(spec.Content[i+1])
 		case "jobTemplate":
 			// CronJob -> jobTemplate -> spec -> template
-			return injectEnvInJobTemplate(spec.Content[i+1], hookEvent, weight)
+			return inj.injectEnvInJobTemplate(spec.Content[i+1])
 		case "containers", "initContainers":
 			// Direct pod spec
-			injectEnvInContainers(spec.Content[i+1], hookEvent, weight)
+			inj.injectEnvInContainers(spec.Content[i+1])
+		case "ephemeralContainers":
+			if inj.ephemeral {
+				inj.injectEnvInContainers(spec.Content[i+1])
+			}
 		}
 	}
 
@@ -589,14 +857,16 @@ func injectEnvInSpec(spec *yaml.Node, hookEvent string, weight int) error {
 }
 
 // injectEnvInPodTemplate handles pod template spec.
-func injectEnvInPodTemplate(template *yaml.Node, hookEvent string, weight int) error {
+func (inj *envInjector) injectEnvInPodTemplate(template *yaml.Node) error {
 	if template.Kind != yaml.MappingNode {
 		return nil
 	}
 
 	for i := 0; i < len(template.Content); i += 2 {
 		if template.Content[i].Value == "spec" {
-			return injectEnvInPodSpec(template.Content[i+1], hookEvent, weight)
+			podSpec := template.Content[i+1]
+			inj.annotatePod(template)
+			return inj.injectEnvInPodSpec(podSpec)
 		}
 	}
 
@@ -604,14 +874,14 @@ func injectEnvInPodTemplate(template *yaml.Node, hookEvent string, weight int) e
 }
 
 // injectEnvInJobTemplate handles CronJob jobTemplate.
-func injectEnvInJobTemplate(jobTemplate *yaml.Node, hookEvent string, weight int) error {
+func (inj *envInjector) injectEnvInJobTemplate(jobTemplate *yaml.Node) error {
 	if jobTemplate.Kind != yaml.MappingNode {
 		return nil
 	}
 
 	for i := 0; i < len(jobTemplate.Content); i += 2 {
 		if jobTemplate.Content[i].Value == "spec" {
-			return injectEnvInSpec(jobTemplate.Content[i+1], hookEvent, weight)
+			return inj.injectEnvInSpec(jobTemplate.Content[i+1])
 		}
 	}
 
@@ -619,15 +889,15 @@ func injectEnvInJobTemplate(jobTemplate *yaml.Node, hookEvent string, weight int
 }
 
 // injectEnvInPodSpec handles container injection in pod spec.
-func injectEnvInPodSpec(podSpec *yaml.Node, hookEvent string, weight int) error {
+func (inj *envInjector) injectEnvInPodSpec(podSpec *yaml.Node) error {
 	if podSpec.Kind != yaml.MappingNode {
 		return nil
 	}
 
 	for i := 0; i < len(podSpec.Content); i += 2 {
 		key := podSpec.Content[i].Value
-		if key == "containers" || key == "initContainers" {
-			injectEnvInContainers(podSpec.Content[i+1], hookEvent, weight)
+		if key == "containers" || key == "initContainers" || (key == "ephemeralContainers" && inj.ephemeral) {
+			inj.injectEnvInContainers(podSpec.Content[i+1])
 		}
 	}
 
@@ -635,20 +905,38 @@ func injectEnvInPodSpec(podSpec *yaml.Node, hookEvent string, weight int) error
 }
 
 // injectEnvInContainers adds env vars to all containers in a list.
-func injectEnvInContainers(containers *yaml.Node, hookEvent string, weight int) {
+func (inj *envInjector) injectEnvInContainers(containers *yaml.Node) {
 	if containers.Kind != yaml.SequenceNode {
 		return
 	}
 
 	for _, container := range containers.Content {
 		if container.Kind == yaml.MappingNode {
-			injectEnvInContainer(container, hookEvent, weight)
+			inj.injectEnvInContainer(container)
 		}
 	}
 }
 
 // injectEnvInContainer adds env vars to a single container.
-func injectEnvInContainer(container *yaml.Node, hookEvent string, weight int) {
+func (inj *envInjector) injectEnvInContainer(container *yaml.Node) {
+	name := ""
+	if v := mappingValue(container, "name"); v != nil {
+		name = v.Value
+	}
+	if !inj.filter.selects(name) {
+		return
+	}
+	if inj.configMap != "" {
+		addEnvFromConfigMap(container, inj.configMap)
+		inj.configMapRefs++
+	}
+	if name != "" {
+		inj.containers = append(inj.containers, name)
+	}
+	if len(inj.vars) == 0 {
+		return
+	}
+
 	// Find or create env array
 	var envNode *yaml.Node
 	var envIndex int
@@ -671,63 +959,108 @@ func injectEnvInContainer(container *yaml.Node, hookEvent string, weight int) {
 		envIndex = len(container.Content) - 1
 	}
 
-	// Add or update HELM_HOOK_EVENT
-	addOrUpdateEnvVar(envNode, "HELM_HOOK_EVENT", hookEvent)
-	addOrUpdateEnvVar(envNode, "HELM_HOOK_WEIGHT", strconv.Itoa(weight))
+	// Add or update each variable in place
+	for _, v := range inj.vars {
+		addOrUpdateEnvVar(envNode, v)
+	}
 
 	container.Content[envIndex] = envNode
 }
 
+// addEnvFromConfigMap adds an envFrom reference to a ConfigMap unless the
+// container already has it.
+func addEnvFromConfigMap(container *yaml.Node, name string) {
+	envFrom := mappingValue(container, "envFrom")
+	if envFrom == nil || envFrom.Kind != yaml.SequenceNode {
+		envFrom = &yaml.Node{Kind: yaml.SequenceNode}
+		setMappingValue(container, "envFrom", envFrom)
+	}
+	for _, item := range envFrom.Content {
+		if ref := mappingValue(mappingValue(item, "configMapRef"), "name"); ref != nil && ref.Value == name {
+			return
+		}
+	}
+	envFrom.Content = append(envFrom.Content, &yaml.Node{Kind: yaml.MappingNode, Content: []*yaml.Node{
+		{Kind: yaml.ScalarNode, Value: "configMapRef"},
+		{Kind: yaml.MappingNode, Content: []*yaml.Node{
+			{Kind: yaml.ScalarNode, Value: "name"},
+			{Kind: yaml.ScalarNode, Value: name},
+		}},
+	}})
+}
+
 // addOrUpdateEnvVar adds or updates an environment variable.
 // Ensures values are always quoted strings for Kubernetes compatibility.
-func addOrUpdateEnvVar(envNode *yaml.Node, name, value string) {
+// Entries the container reads with valueFrom are left unchanged, and
+// optional variables are only added when missing.
+func addOrUpdateEnvVar(envNode *yaml.Node, v envVar) {
 	if envNode.Kind != yaml.SequenceNode {
 		return
 	}
 
 	// Check if var already exists
 	for _, item := range envNode.Content {
-		if item.Kind == yaml.MappingNode {
-			for i := 0; i < len(item.Content); i += 2 {
-				if item.Content[i].Value == "name" && item.Content[i+1].Value == name {
-					// Update existing value
-					for j := 0; j < len(item.Content); j += 2 {
-						if item.Content[j].Value == "value" {
-							item.Content[j+1].Value = value
-							item.Content[j+1].Tag = "!!str"
-							item.Content[j+1].Style = yaml.DoubleQuotedStyle
-							return
-						}
-					}
-					// Add value field if missing
-					item.Content = append(item.Content,
-						&yaml.Node{Kind: yaml.ScalarNode, Value: "value"},
-						&yaml.Node{Kind: yaml.ScalarNode, Value: value, Tag: "!!str", Style: yaml.DoubleQuotedStyle},
-					)
-					return
-				}
-			}
+		if item.Kind != yaml.MappingNode {
+			continue
+		}
+		if name := mappingValue(item, "name"); name == nil || name.Value != v.Name {
+			continue
+		}
+		if v.Optional || mappingValue(item, "valueFrom") != nil {
+			return
 		}
+		// Update existing value
+		if v.FieldPath != "" {
+			removeMappingKey(item, "value")
+		}
+		setMappingValue(item, envValueKey(v), envValueNode(v))
+		return
 	}
 
-	// Add new env var with quoted string value
-	newEnv := &yaml.Node{
+	// Add new env var
+	envNode.Content = append(envNode.Content, &yaml.Node{
 		Kind: yaml.MappingNode,
 		Content: []*yaml.Node{
 			{Kind: yaml.ScalarNode, Value: "name"},
-			{Kind: yaml.ScalarNode, Value: name},
-			{Kind: yaml.ScalarNode, Value: "value"},
-			{Kind: yaml.ScalarNode, Value: value, Tag: "!!str", Style: yaml.DoubleQuotedStyle},
+			{Kind: yaml.ScalarNode, Value: v.Name},
+			{Kind: yaml.ScalarNode, Value: envValueKey(v)},
+			envValueNode(v),
 		},
+	})
+}
+
+// envValueKey returns the key of an env entry holding v: value or valueFrom.
+func envValueKey(v envVar) string {
+	if v.FieldPath != "" {
+		return "valueFrom"
 	}
-	envNode.Content = append(envNode.Content, newEnv)
+	return "value"
+}
+
+// envValueNode returns the quoted value of v, or its valueFrom fieldRef.
+func envValueNode(v envVar) *yaml.Node {
+	if v.FieldPath == "" {
+		return &yaml.Node{Kind: yaml.ScalarNode, Value: v.Value, Tag: "!!str", Style: yaml.DoubleQuotedStyle}
+	}
+	return &yaml.Node{Kind: yaml.MappingNode, Content: []*yaml.Node{
+		{Kind: yaml.ScalarNode, Value: "fieldRef"},
+		{Kind: yaml.MappingNode, Content: []*yaml.Node{
+			{Kind: yaml.ScalarNode, Value: "fieldPath"},
+			{Kind: yaml.ScalarNode, Value: v.FieldPath},
+		}},
+	}}
 }
 
 // marshalNode converts a YAML node back to bytes.
 func marshalNode(node *yaml.Node) ([]byte, error) {
+	return marshalNodeIndent(node, 2)
+}
+
+// marshalNodeIndent converts a YAML node back to bytes using the given indentation.
+func marshalNodeIndent(node *yaml.Node, indent int) ([]byte, error) {
 	var buf bytes.Buffer
 	encoder := yaml.NewEncoder(&buf)
-	encoder.SetIndent(2)
+	encoder.SetIndent(indent)
 	if err := encoder.Encode(node); err != nil {
 		return nil, err
 	}
@@ -736,19 +1069,3 @@ func marshalNode(node *yaml.Node) ([]byte, error) {
 	}
 	return buf.Bytes(), nil
 }
-
-// combineDocuments joins multiple YAML documents with separators.
-func combineDocuments(docs [][]byte) []byte {
-	if len(docs) == 0 {
-		return nil
-	}
-
-	var result bytes.Buffer
-	for i, doc := range docs {
-		if i > 0 {
-			result.WriteString("---\n")
-		}
-		result.Write(doc)
-	}
-	return result.Bytes()
-}
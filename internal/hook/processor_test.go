@@ -1,6 +1,9 @@
 package hook
 
 import (
+	"bytes"
+	"context"
+	"errors"
 	"strings"
 	"testing"
 )
@@ -267,3 +270,195 @@ spec:
 		t.Errorf("Expected 'count' error, got: %v", err)
 	}
 }
+
+func TestProcess_DeletePolicies(t *testing.T) {
+	input := `apiVersion: batch/v1
+kind: Job
+metadata:
+  name: myapp-migration
+  annotations:
+    helm.sh/hook: pre-install,post-upgrade
+    helm.sh/hook-delete-policy: before-hook-creation
+    helm.sh/hook-delete-policies: "post-upgrade=hook-succeeded|hook-failed"
+spec:
+  template:
+    spec:
+      containers:
+        - name: migrate
+          image: busybox
+`
+
+	output, err := Process([]byte(input))
+	if err != nil {
+		t.Fatalf("Process failed: %v", err)
+	}
+
+	docs := strings.Split(string(output), "---\n")
+	if len(docs) != 2 {
+		t.Fatalf("Expected 2 documents, got %d", len(docs))
+	}
+
+	// pre-install keeps the inherited policy
+	if !strings.Contains(docs[0], "helm.sh/hook-delete-policy: before-hook-creation") {
+		t.Errorf("Expected inherited policy on pre-install clone, got:\n%s", docs[0])
+	}
+	if !strings.Contains(docs[1], `helm.sh/hook-delete-policy: "hook-succeeded,hook-failed"`) {
+		t.Errorf("Expected per-hook policy on post-upgrade clone, got:\n%s", docs[1])
+	}
+	if strings.Contains(string(output), "hook-delete-policies") {
+		t.Error("hook-delete-policies should be removed after processing")
+	}
+}
+
+func TestProcess_DeletePoliciesPositional(t *testing.T) {
+	input := `apiVersion: batch/v1
+kind: Job
+metadata:
+  name: myapp-init
+  annotations:
+    helm.sh/hook: pre-install
+    helm.sh/hook-delete-policies: "hook-succeeded"
+spec:
+  template:
+    spec:
+      containers:
+        - name: init
+          image: busybox
+`
+
+	output, err := Process([]byte(input))
+	if err != nil {
+		t.Fatalf("Process failed: %v", err)
+	}
+
+	if !strings.Contains(string(output), `helm.sh/hook-delete-policy: "hook-succeeded"`) {
+		t.Errorf("Expected delete policy on single hook, got:\n%s", output)
+	}
+}
+
+func TestProcess_InvalidDeletePolicy(t *testing.T) {
+	input := `apiVersion: batch/v1
+kind: Job
+metadata:
+  name: myapp-init
+  annotations:
+    helm.sh/hook: pre-install,post-install
+    helm.sh/hook-delete-policies: "pre-install=hook-sucseeded"
+spec:
+  template:
+    spec:
+      containers:
+        - name: init
+          image: busybox
+`
+
+	_, err := Process([]byte(input))
+	if err == nil {
+		t.Fatal("Expected error for unknown delete policy")
+	}
+	if !strings.Contains(err.Error(), "unknown policy") {
+		t.Errorf("Expected 'unknown policy' error, got: %v", err)
+	}
+
+	err = ValidateAnnotations(map[string]string{
+		annotationHook:               "pre-install,post-install",
+		annotationHookDeletePolicies: "pre-install=hook-sucseeded",
+	}, "myapp-init")
+	if err == nil {
+		t.Fatal("Expected ValidateAnnotations to reject unknown delete policy")
+	}
+}
+
+func TestProcessStream_Output(t *testing.T) {
+	// A comment-only document is kept; empty trailing documents are not
+	input := `apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: myapp-config
+---
+# Source: chart/templates/disabled.yaml
+---
+apiVersion: batch/v1
+kind: Job
+metadata:
+  name: myapp-migration
+  annotations:
+    helm.sh/hook: pre-install,post-upgrade
+    helm.sh/hook-env: "false"
+spec:
+  template:
+    spec:
+      containers:
+        - name: migrate
+          image: busybox
+---
+---
+`
+	expected := `apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: myapp-config
+---
+# Source: chart/templates/disabled.yaml
+---
+apiVersion: batch/v1
+kind: Job
+metadata:
+  name: myapp-migration-pre-install
+  annotations:
+    helm.sh/hook: "pre-install"
+    helm.sh/hook-env: "false"
+    helm.sh/hook-weight: "0"
+spec:
+  template:
+    spec:
+      containers:
+        - name: migrate
+          image: busybox
+---
+apiVersion: batch/v1
+kind: Job
+metadata:
+  name: myapp-migration-post-upgrade
+  annotations:
+    helm.sh/hook: "post-upgrade"
+    helm.sh/hook-env: "false"
+    helm.sh/hook-weight: "0"
+spec:
+  template:
+    spec:
+      containers:
+        - name: migrate
+          image: busybox
+`
+
+	var buf bytes.Buffer
+	if err := ProcessStream(context.Background(), strings.NewReader(input), &buf); err != nil {
+		t.Fatalf("ProcessStream failed: %v", err)
+	}
+	if buf.String() != expected {
+		t.Errorf("Unexpected ProcessStream output:\n%s\nexpected:\n%s", buf.String(), expected)
+	}
+
+	output, err := Process([]byte(input))
+	if err != nil {
+		t.Fatalf("Process failed: %v", err)
+	}
+	if string(output) != expected {
+		t.Errorf("Unexpected Process output:\n%s\nexpected:\n%s", output, expected)
+	}
+}
+
+func TestProcessStream_Cancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	var buf bytes.Buffer
+	err := ProcessStream(ctx, strings.NewReader("kind: ConfigMap\n"), &buf)
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected context.Canceled, got: %v", err)
+	}
+	if buf.Len() != 0 {
+		t.Errorf("Expected no output after cancellation, got %q", buf.String())
+	}
+}
@@ -0,0 +1,107 @@
+package hook
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"io"
+)
+
+// documentReader splits a YAML stream into the raw bytes of each document,
+// so documents that need no changes can be copied to the output verbatim.
+type documentReader struct {
+	r       *bufio.Reader
+	pending []byte
+	eof     bool
+}
+
+// newDocumentReader returns a documentReader reading from r.
+func newDocumentReader(r io.Reader) *documentReader {
+	return &documentReader{r: bufio.NewReader(r)}
+}
+
+// next returns the raw bytes of the next document, without its "---"
+// separator line. Documents containing only blank lines are skipped.
+// Returns io.EOF when the stream is exhausted.
+func (d *documentReader) next() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.Write(d.pending)
+	d.pending = nil
+
+	for !d.eof {
+		line, err := d.r.ReadBytes('\n')
+		if err != nil {
+			if !errors.Is(err, io.EOF) {
+				return nil, err
+			}
+			d.eof = true
+		}
+		if len(line) == 0 {
+			continue
+		}
+
+		rest, isMarker := documentMarker(line)
+		if !isMarker {
+			buf.Write(line)
+			continue
+		}
+
+		if hasContent(buf.Bytes()) {
+			d.pending = rest
+			return buf.Bytes(), nil
+		}
+		buf.Reset()
+		buf.Write(rest)
+	}
+
+	if hasContent(buf.Bytes()) {
+		return buf.Bytes(), nil
+	}
+	return nil, io.EOF
+}
+
+// documentMarker reports whether line is a "---" or "..." document marker.
+// Any content following "---" on the same line (e.g. a comment) is
+// returned so it stays with the document that follows.
+func documentMarker(line []byte) ([]byte, bool) {
+	trimmed := bytes.TrimRight(line, "\r\n")
+	for _, marker := range [][]byte{[]byte("---"), []byte("...")} {
+		if !bytes.HasPrefix(trimmed, marker) {
+			continue
+		}
+		rest := trimmed[len(marker):]
+		if len(rest) == 0 {
+			return nil, true
+		}
+		if rest[0] == ' ' || rest[0] == '\t' {
+			rest = bytes.TrimSpace(rest)
+			if len(rest) == 0 {
+				return nil, true
+			}
+			return append(rest, '\n'), true
+		}
+	}
+	return nil, false
+}
+
+// hasContent reports whether doc contains anything besides whitespace.
+func hasContent(doc []byte) bool {
+	return len(bytes.TrimSpace(doc)) > 0
+}
+
+// detectIndent guesses the block indentation used by a raw document,
+// so re-encoded documents keep the style of their input. Defaults to 2.
+func detectIndent(doc []byte) int {
+	for _, line := range bytes.Split(doc, []byte("\n")) {
+		trimmed := bytes.TrimLeft(line, " ")
+		indent := len(line) - len(trimmed)
+		if indent == 0 || len(bytes.TrimSpace(trimmed)) == 0 || trimmed[0] == '#' {
+			continue
+		}
+		if indent < 2 || indent > 8 {
+			return 2
+		}
+		return indent
+	}
+	return 2
+}
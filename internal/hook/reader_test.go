@@ -0,0 +1,100 @@
+package hook
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestDocumentReader_Split(t *testing.T) {
+	input := "---\n# Source: a.yaml\nkind: A\n---\n\n--- # Source: b.yaml\nkind: B\n...\nkind: C"
+	reader := newDocumentReader(strings.NewReader(input))
+
+	expected := []string{
+		"# Source: a.yaml\nkind: A\n",
+		"# Source: b.yaml\nkind: B\n",
+		"kind: C",
+	}
+	for i, want := range expected {
+		doc, err := reader.next()
+		if err != nil {
+			t.Fatalf("Document %d: unexpected error: %v", i, err)
+		}
+		if string(doc) != want {
+			t.Errorf("Document %d: expected %q, got %q", i, want, doc)
+		}
+	}
+
+	if _, err := reader.next(); !errors.Is(err, io.EOF) {
+		t.Errorf("Expected EOF, got: %v", err)
+	}
+}
+
+func TestProcess_PreservesUntouchedDocuments(t *testing.T) {
+	nonHook := `# Source: chart/templates/configmap.yaml
+apiVersion: v1
+kind: ConfigMap
+metadata:
+    name: myapp-config   # keep this comment
+data:
+    z-first: "double"
+    a-second: 'single'
+    script: >
+        folded text
+        stays folded
+    list:
+    - a
+    - b
+`
+	input := "---\n" + nonHook + `---
+apiVersion: batch/v1
+kind: Job
+metadata:
+    name: myapp-init
+    annotations:
+        helm.sh/hook: pre-install
+        helm.sh/hook-env: "false"
+`
+
+	output, err := Process([]byte(input))
+	if err != nil {
+		t.Fatalf("Process failed: %v", err)
+	}
+
+	docs := strings.Split(string(output), "---\n")
+	if len(docs) != 2 {
+		t.Fatalf("Expected 2 documents, got %d:\n%s", len(docs), output)
+	}
+	if docs[0] != nonHook {
+		t.Errorf("Non-hook document not preserved:\n%s\nvs\n%s", docs[0], nonHook)
+	}
+	if !strings.HasPrefix(input[strings.Index(input, "---\napiVersion: batch/v1")+4:], docs[1]) {
+		t.Errorf("Unchanged hook document not preserved:\n%s", docs[1])
+	}
+}
+
+func TestProcess_KeepsIndentation(t *testing.T) {
+	input := `apiVersion: batch/v1
+kind: Job
+metadata:
+    name: myapp-init
+    annotations:
+        helm.sh/hook: pre-install
+spec:
+    template:
+        spec:
+            containers:
+                - name: init
+                  image: busybox
+`
+
+	output, err := Process([]byte(input))
+	if err != nil {
+		t.Fatalf("Process failed: %v", err)
+	}
+
+	if !strings.Contains(string(output), "\n    name: myapp-init\n") {
+		t.Errorf("Expected 4-space indentation to be kept, got:\n%s", output)
+	}
+}
@@ -0,0 +1,185 @@
+package hook
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// releaseObject is one output resource as seen by the release validator.
+type releaseObject struct {
+	// document is the 1-based position of the input document it came from.
+	document int
+	// group is the API group of the object, "" for the core group.
+	group        string
+	kind         string
+	namespace    string
+	name         string
+	events       []string
+	weight       int
+	deletePolicy string
+}
+
+// ref formats the object as "Kind/name" or "Kind/namespace/name".
+func (o *releaseObject) ref() string {
+	if o.namespace == "" {
+		return o.kind + "/" + o.name
+	}
+	return o.kind + "/" + o.namespace + "/" + o.name
+}
+
+// key identifies the object within the release, like ref but with the
+// API group, e.g. "Certificate.cert-manager.io/tls", so that same-named
+// kinds of different groups are told apart.
+func (o *releaseObject) key() string {
+	if o.group == "" {
+		return o.ref()
+	}
+	kind := o.kind + "." + o.group
+	if o.namespace == "" {
+		return kind + "/" + o.name
+	}
+	return kind + "/" + o.namespace + "/" + o.name
+}
+
+// releaseValidator checks the resources produced for a whole release for
+// problems no single document shows: duplicate objects, delete policy
+// conflicts between same-named hooks and, with Config.StrictOrdering,
+// hooks of one event sharing a weight.
+type releaseValidator struct {
+	p *Processor
+	// objects holds every output by key.
+	objects map[string][]*releaseObject
+	// weights holds the first hook seen for each event and weight.
+	weights map[string]*releaseObject
+}
+
+// newReleaseValidator returns an empty validator.
+func (p *Processor) newReleaseValidator() *releaseValidator {
+	return &releaseValidator{
+		p:       p,
+		objects: make(map[string][]*releaseObject),
+		weights: make(map[string]*releaseObject),
+	}
+}
+
+// add checks the outputs of one input document against everything seen
+// so far and returns the problems found.
+func (v *releaseValidator) add(document int, nodes []*yaml.Node) []Problem {
+	var problems []Problem
+	for _, node := range nodes {
+		problems = append(problems, v.addNode(document, node)...)
+	}
+	return problems
+}
+
+// addNode checks a single output resource, descending into List documents.
+func (v *releaseValidator) addNode(document int, node *yaml.Node) []Problem {
+	res, err := parseResource(node)
+	if err != nil || res.Kind == "" {
+		return nil
+	}
+	if items := listItems(node, res); items != nil {
+		var problems []Problem
+		for _, item := range items.Content {
+			problems = append(problems, v.addNode(document, item)...)
+		}
+		return problems
+	}
+
+	content := node
+	if node.Kind == yaml.DocumentNode && len(node.Content) > 0 {
+		content = node.Content[0]
+	}
+
+	obj := &releaseObject{document: document, kind: res.Kind, name: res.Name}
+	if group, _, ok := strings.Cut(res.APIVersion, "/"); ok {
+		obj.group = group
+	}
+	if ns := mappingValue(mappingValue(content, "metadata"), "namespace"); ns != nil {
+		obj.namespace = ns.Value
+	}
+
+	// Hook checks read Helm annotations, which other profiles replace
+	if hookValue, ok := res.Annotations[annotationHook]; ok {
+		obj.events = parseHookEvents(hookValue)
+		obj.weight, _ = strconv.Atoi(strings.TrimSpace(res.Annotations[annotationHookWeight]))
+		obj.deletePolicy = res.Annotations[annotationHookDeletePolicy]
+	}
+
+	var problems []Problem
+	report := func(annotation, format string, args ...interface{}) {
+		problems = append(problems, Problem{
+			Document:   document,
+			Kind:       obj.kind,
+			Name:       obj.name,
+			Annotation: annotation,
+			Message:    fmt.Sprintf(format, args...),
+		})
+	}
+
+	// Duplicate objects. Same-named hooks of different events are allowed
+	// when Helm deletes each before creating the next.
+	key := obj.key()
+	duplicate := false
+	for _, other := range v.objects[key] {
+		if len(obj.events) == 0 || len(other.events) == 0 || sharesEvent(obj.events, other.events) {
+			report("", "duplicate %s (also in document %d)", key, other.document)
+			duplicate = true
+			continue
+		}
+		if !hasBeforeHookCreation(obj.deletePolicy) {
+			report(annotationHookDeletePolicy, "delete policy %q conflicts with same-named %s hook in document %d; %s hook needs before-hook-creation",
+				obj.deletePolicy, strings.Join(other.events, ","), other.document, strings.Join(obj.events, ","))
+		} else if !hasBeforeHookCreation(other.deletePolicy) {
+			report(annotationHookDeletePolicy, "same-named %s hook in document %d has delete policy %q without before-hook-creation",
+				strings.Join(other.events, ","), other.document, other.deletePolicy)
+		}
+	}
+	v.objects[key] = append(v.objects[key], obj)
+
+	// Shared weights within one event; a duplicate is already reported.
+	// Generated env ConfigMaps sit one weight below their hook by design.
+	if v.p.cfg.StrictOrdering && !duplicate && !v.p.generated[content] {
+		for _, event := range obj.events {
+			weightKey := event + "/" + strconv.Itoa(obj.weight)
+			if other, ok := v.weights[weightKey]; ok {
+				report(annotationHookWeight, "%s hook has the same weight %d as %s in document %d; strict ordering requires distinct weights",
+					event, obj.weight, other.ref(), other.document)
+				continue
+			}
+			v.weights[weightKey] = obj
+		}
+	}
+
+	return problems
+}
+
+// sharesEvent reports whether two hook event lists have an event in common.
+func sharesEvent(a, b []string) bool {
+	for _, x := range a {
+		for _, y := range b {
+			if x == y {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// hasBeforeHookCreation reports whether a helm.sh/hook-delete-policy value
+// deletes the previous resource before a new hook is created. This is
+// Helm's behaviour when no policy is set.
+func hasBeforeHookCreation(policy string) bool {
+	if strings.TrimSpace(policy) == "" {
+		return true
+	}
+	for _, p := range strings.Split(policy, ",") {
+		if strings.TrimSpace(p) == "before-hook-creation" {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,169 @@
+package hook
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestProcess_DuplicateObjects(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{
+			name: "plain resources",
+			input: `apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: config
+---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: config
+`,
+			want: "document 2: ConfigMap/config: duplicate ConfigMap/config (also in document 1)",
+		},
+		{
+			name: "generated name collides",
+			input: dependsJob("myapp-pre-install", `    helm.sh/hook: pre-install
+`) + "---\n" + dependsJob("myapp", `    helm.sh/hook: pre-install,post-install
+`),
+			want: "document 2: Job/myapp-pre-install: duplicate Job.batch/myapp-pre-install (also in document 1)",
+		},
+		{
+			name: "suffix-disabled split with conflicting policy",
+			input: dependsJob("myapp", `    helm.sh/hook: pre-install,post-install
+    helm.sh/hook-name-suffix: "false"
+    helm.sh/hook-delete-policies: "post-install=hook-succeeded"
+`),
+			want: `document 1: Job/myapp: helm.sh/hook-delete-policy: delete policy "hook-succeeded" conflicts with same-named pre-install hook in document 1`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Process([]byte(tt.input))
+			if err == nil || !strings.HasPrefix(err.Error(), tt.want) {
+				t.Errorf("Expected error starting with %q, got: %v", tt.want, err)
+			}
+		})
+	}
+}
+
+func TestProcess_SameNameDifferentNamespace(t *testing.T) {
+	input := `apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: config
+  namespace: a
+---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: config
+  namespace: b
+`
+
+	if _, err := Process([]byte(input)); err != nil {
+		t.Errorf("Expected no error for different namespaces, got: %v", err)
+	}
+}
+
+func TestProcess_SameKindDifferentGroup(t *testing.T) {
+	input := `apiVersion: cert-manager.io/v1
+kind: Certificate
+metadata:
+  name: tls
+---
+apiVersion: networking.gke.io/v1
+kind: Certificate
+metadata:
+  name: tls
+`
+
+	if _, err := Process([]byte(input)); err != nil {
+		t.Errorf("Expected no error for different API groups, got: %v", err)
+	}
+
+	// The version is not part of the object's identity
+	input = strings.Replace(input, "networking.gke.io/v1", "cert-manager.io/v1beta1", 1)
+	_, err := Process([]byte(input))
+	if err == nil || !strings.Contains(err.Error(), "duplicate Certificate.cert-manager.io/tls (also in document 1)") {
+		t.Errorf("Expected duplicate error, got: %v", err)
+	}
+}
+
+func TestProcess_SuffixDisabledSplitDefaultPolicy(t *testing.T) {
+	// Helm deletes the previous hook before creating the next by default
+	input := dependsJob("myapp", `    helm.sh/hook: pre-install,post-install
+    helm.sh/hook-name-suffix: "false"
+`)
+
+	if _, err := Process([]byte(input)); err != nil {
+		t.Errorf("Expected no error, got: %v", err)
+	}
+}
+
+func TestProcess_StrictOrdering(t *testing.T) {
+	input := dependsJob("a", `    helm.sh/hook: pre-install
+    helm.sh/hook-weight: "5"
+`) + "---\n" + dependsJob("b", `    helm.sh/hook: pre-install,post-install
+    helm.sh/hook-weights: "pre-install=5,post-install=5"
+`)
+
+	if _, err := Process([]byte(input)); err != nil {
+		t.Fatalf("Shared weights should be allowed by default, got: %v", err)
+	}
+
+	cfg := DefaultConfig()
+	cfg.StrictOrdering = true
+	_, err := NewProcessor(cfg).Process([]byte(input))
+	want := "document 2: Job/b-pre-install: helm.sh/hook-weight: pre-install hook has the same weight 5 as Job/a in document 1"
+	if err == nil || !strings.HasPrefix(err.Error(), want) {
+		t.Errorf("Expected error starting with %q, got: %v", want, err)
+	}
+}
+
+func TestLint_ReleaseProblems(t *testing.T) {
+	input := dependsJob("a", `    helm.sh/hook: pre-install
+`) + "---\n" + dependsJob("a", `    helm.sh/hook: pre-install
+`) + "---\n" + dependsJob("b", `    helm.sh/hook: pre-install
+`)
+
+	cfg := DefaultConfig()
+	cfg.StrictOrdering = true
+	problems := NewProcessor(cfg).Lint([]byte(input))
+
+	// Document 2 duplicates a; b shares weight 0 with a
+	if len(problems) != 2 {
+		t.Fatalf("Expected 2 problems, got %v", problems)
+	}
+	if problems[0].Document != 2 || !strings.Contains(problems[0].Message, "duplicate Job.batch/a") {
+		t.Errorf("Unexpected first problem: %v", problems[0])
+	}
+	if problems[1].Document != 3 || problems[1].Annotation != annotationHookWeight {
+		t.Errorf("Unexpected second problem: %v", problems[1])
+	}
+}
+
+func TestProcess_StrictOrderingEnvConfigMap(t *testing.T) {
+	// The ConfigMap of migrate gets weight 4, like schema
+	input := dependsJob("schema", `    helm.sh/hook: pre-install
+    helm.sh/hook-weight: "4"
+`) + "---\n" + dependsJob("migrate", `    helm.sh/hook: pre-install
+    helm.sh/hook-weight: "5"
+    helm.sh/hook-env-mode: envFrom
+`)
+
+	cfg := DefaultConfig()
+	cfg.StrictOrdering = true
+	p := NewProcessor(cfg)
+	if _, err := p.Process([]byte(input)); err != nil {
+		t.Errorf("Expected generated ConfigMaps to be exempt, got: %v", err)
+	}
+	if problems := p.Lint([]byte(input)); len(problems) != 0 {
+		t.Errorf("Expected no lint problems, got %v", problems)
+	}
+}
@@ -0,0 +1,183 @@
+package hook
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"sort"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Actions recorded in a DocumentReport.
+const (
+	// ActionPassthrough means the document was copied unchanged.
+	ActionPassthrough = "passthrough"
+	// ActionEnhanced means the document was modified in place.
+	ActionEnhanced = "enhanced"
+	// ActionSplit means the document was replaced by one clone per hook event.
+	ActionSplit = "split"
+)
+
+// Report records what processing did to each input document.
+type Report struct {
+	Documents []DocumentReport `json:"documents"`
+}
+
+// DocumentReport describes one input document, or one item of a List document.
+type DocumentReport struct {
+	// Document is the 1-based position of the document in the input.
+	// It is zero for List items.
+	Document int    `json:"document,omitempty"`
+	Kind     string `json:"kind,omitempty"`
+	// Name is the name of the resource before processing.
+	Name    string         `json:"name,omitempty"`
+	Action  string         `json:"action"`
+	Outputs []OutputReport `json:"outputs,omitempty"`
+	// Items holds the reports of a List document's items.
+	Items []DocumentReport `json:"items,omitempty"`
+}
+
+// OutputReport describes one hook resource produced from an input document.
+// Event and Weight are the Helm values, also for the argocd profile.
+type OutputReport struct {
+	Name string `json:"name"`
+	// Truncated is set when the generated name exceeded the length limit
+	// and was shortened with a hash.
+	Truncated bool   `json:"truncated,omitempty"`
+	Event     string `json:"event"`
+	Weight    int    `json:"weight"`
+	// EnvContainers lists the containers env vars were injected into.
+	EnvContainers []string `json:"envContainers,omitempty"`
+	// Generated is set for resources created by helm-hooks, such as the
+	// ConfigMaps of the envFrom env mode.
+	Generated bool `json:"generated,omitempty"`
+}
+
+// ProcessWithReport is Process that also returns a Report, using the
+// default configuration.
+func ProcessWithReport(input []byte) ([]byte, *Report, error) {
+	return defaultProcessor.ProcessWithReport(input)
+}
+
+// ProcessWithReport is Process that also returns a Report of what was
+// done to each document.
+func (p *Processor) ProcessWithReport(input []byte) ([]byte, *Report, error) {
+	var buf bytes.Buffer
+	report, err := p.ProcessStreamWithReport(context.Background(), bytes.NewReader(input), &buf)
+	if err != nil {
+		return nil, nil, err
+	}
+	return buf.Bytes(), report, nil
+}
+
+// ProcessStreamWithReport is ProcessStream that also returns a Report of
+// what was done to each document. Documents are reported in input order.
+func (p *Processor) ProcessStreamWithReport(ctx context.Context, r io.Reader, w io.Writer) (*Report, error) {
+	rec := &reportRecorder{report: &Report{Documents: []DocumentReport{}}}
+	recording := *p
+	recording.recorder = rec
+	if err := recording.ProcessStream(ctx, r, w); err != nil {
+		return nil, err
+	}
+
+	// Documents with dependencies are processed last
+	sort.SliceStable(rec.report.Documents, func(i, j int) bool {
+		return rec.report.Documents[i].Document < rec.report.Documents[j].Document
+	})
+	return rec.report, nil
+}
+
+// reportRecorder builds a Report while documents are processed. All
+// methods are no-ops on a nil recorder, so processing code can call them
+// unconditionally.
+type reportRecorder struct {
+	report *Report
+	// index is the position of the top-level document being processed.
+	index int
+	// stack holds the document being processed and, for List items,
+	// its enclosing lists.
+	stack []*DocumentReport
+}
+
+// at sets the position of the next top-level document.
+func (r *reportRecorder) at(index int) {
+	if r == nil {
+		return
+	}
+	r.index = index
+}
+
+// begin starts the report for a resource.
+func (r *reportRecorder) begin(res *Resource) {
+	if r == nil {
+		return
+	}
+	doc := &DocumentReport{Kind: res.Kind, Name: res.Name, Action: ActionPassthrough}
+	if len(r.stack) == 0 {
+		doc.Document = r.index
+	}
+	r.stack = append(r.stack, doc)
+}
+
+// end finishes the current resource's report and attaches it to its
+// List, or to the Report for a top-level document.
+func (r *reportRecorder) end() {
+	if r == nil || len(r.stack) == 0 {
+		return
+	}
+	doc := r.stack[len(r.stack)-1]
+	r.stack = r.stack[:len(r.stack)-1]
+
+	if len(r.stack) == 0 {
+		r.report.Documents = append(r.report.Documents, *doc)
+		return
+	}
+	parent := r.stack[len(r.stack)-1]
+	parent.Items = append(parent.Items, *doc)
+	// A list is as changed as its most changed item
+	if doc.Action == ActionSplit || (doc.Action == ActionEnhanced && parent.Action == ActionPassthrough) {
+		parent.Action = doc.Action
+	}
+}
+
+// setAction records what was done to the current resource.
+func (r *reportRecorder) setAction(action string) {
+	if r == nil || len(r.stack) == 0 {
+		return
+	}
+	r.stack[len(r.stack)-1].Action = action
+}
+
+// addOutput records a resource produced for the current resource.
+func (r *reportRecorder) addOutput(out OutputReport) {
+	if r == nil || len(r.stack) == 0 {
+		return
+	}
+	doc := r.stack[len(r.stack)-1]
+	doc.Outputs = append(doc.Outputs, out)
+}
+
+// keepOutputs drops the recorded outputs that are not among nodes, e.g.
+// clones the argocd profile discarded.
+func (r *reportRecorder) keepOutputs(nodes []*yaml.Node) {
+	if r == nil || len(r.stack) == 0 {
+		return
+	}
+	kept := make(map[string]int)
+	for _, node := range nodes {
+		if res, err := parseResource(node); err == nil {
+			kept[res.Name]++
+		}
+	}
+
+	doc := r.stack[len(r.stack)-1]
+	var outputs []OutputReport
+	for _, out := range doc.Outputs {
+		if kept[out.Name] > 0 {
+			kept[out.Name]--
+			outputs = append(outputs, out)
+		}
+	}
+	doc.Outputs = outputs
+}
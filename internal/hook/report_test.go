@@ -0,0 +1,102 @@
+package hook
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestProcessWithReport(t *testing.T) {
+	input := `apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: config
+---
+` + dependsJob("myapp-database-migration-with-a-rather-long-name-here", `    helm.sh/hook: test,post-upgrade
+    helm.sh/hook-weights: "test=-5,post-upgrade=10"
+`) + "---\n" + dependsJob("seed", `    helm.sh/hook: post-install
+    helm.sh/hook-weight: "3"
+`)
+
+	_, report, err := ProcessWithReport([]byte(input))
+	if err != nil {
+		t.Fatalf("ProcessWithReport failed: %v", err)
+	}
+
+	want := []DocumentReport{
+		{Document: 1, Kind: "ConfigMap", Name: "config", Action: ActionPassthrough},
+		{Document: 2, Kind: "Job", Name: "myapp-database-migration-with-a-rather-long-name-here", Action: ActionSplit, Outputs: []OutputReport{
+			{Name: "myapp-database-migration-with-a-rather-long-name-here-test", Event: "test", Weight: -5, EnvContainers: []string{"main"}},
+			{Name: "myapp-database-migration-with-a-rather-lo-post-upgrade-f21c1e37", Truncated: true, Event: "post-upgrade", Weight: 10, EnvContainers: []string{"main"}},
+		}},
+		{Document: 3, Kind: "Job", Name: "seed", Action: ActionEnhanced, Outputs: []OutputReport{
+			{Name: "seed", Event: "post-install", Weight: 3, EnvContainers: []string{"main"}},
+		}},
+	}
+	if !reflect.DeepEqual(report.Documents, want) {
+		t.Errorf("report = %+v\nwant %+v", report.Documents, want)
+	}
+}
+
+func TestProcessWithReport_EnvDisabledAndLists(t *testing.T) {
+	input := `apiVersion: v1
+kind: List
+items:
+  - apiVersion: v1
+    kind: ConfigMap
+    metadata:
+      name: config
+  - apiVersion: batch/v1
+    kind: Job
+    metadata:
+      name: job
+      annotations:
+        helm.sh/hook: pre-install,pre-upgrade
+        helm.sh/hook-env: "false"
+    spec:
+      template:
+        spec:
+          containers:
+            - name: main
+              image: busybox
+`
+
+	_, report, err := ProcessWithReport([]byte(input))
+	if err != nil {
+		t.Fatalf("ProcessWithReport failed: %v", err)
+	}
+
+	if len(report.Documents) != 1 {
+		t.Fatalf("Expected 1 document, got %+v", report.Documents)
+	}
+	list := report.Documents[0]
+	if list.Action != ActionSplit || len(list.Items) != 2 {
+		t.Fatalf("Expected split list with 2 items, got %+v", list)
+	}
+	if list.Items[0].Action != ActionPassthrough {
+		t.Errorf("Expected passthrough ConfigMap, got %+v", list.Items[0])
+	}
+	job := list.Items[1]
+	if job.Action != ActionSplit || len(job.Outputs) != 2 {
+		t.Fatalf("Expected split Job with 2 outputs, got %+v", job)
+	}
+	if job.Outputs[1].Name != "job-pre-upgrade" || job.Outputs[1].EnvContainers != nil {
+		t.Errorf("Expected job-pre-upgrade without env containers, got %+v", job.Outputs[1])
+	}
+}
+
+func TestProcessWithReport_ArgoCDDroppedClone(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Profile = ProfileArgoCD
+	input := dependsJob("myapp", `    helm.sh/hook: pre-install,pre-upgrade
+`)
+
+	_, report, err := NewProcessor(cfg).ProcessWithReport([]byte(input))
+	if err != nil {
+		t.Fatalf("ProcessWithReport failed: %v", err)
+	}
+
+	outputs := report.Documents[0].Outputs
+	if len(outputs) != 1 || outputs[0].Name != "myapp-pre-install" {
+		t.Errorf("Expected only the kept pre-install clone, got %+v", outputs)
+	}
+}
@@ -1,37 +1,35 @@
 package hook
 
 import (
+	"fmt"
+
 	"gopkg.in/yaml.v3"
 )
 
 // splitResource creates separate resources for each hook event.
-func splitResource(node *yaml.Node, res *Resource, hooks []string, weights map[string]int, envEnabled, nameSuffixEnabled bool) ([][]byte, error) {
-	var results [][]byte
+func (p *Processor) splitResource(node *yaml.Node, res *Resource, specs []hookSpec, envEnabled, nameSuffixEnabled bool) ([]*yaml.Node, error) {
+	var results []*yaml.Node
+
+	// Generate new names
+	names, truncated, err := p.splitNames(res, specs, nameSuffixEnabled)
+	if err != nil {
+		return nil, fmt.Errorf("resource %q: %w", res.Name, err)
+	}
 
-	for _, hookEvent := range hooks {
+	for i, spec := range specs {
 		// Deep clone the node
 		cloned, err := cloneNode(node)
 		if err != nil {
 			return nil, err
 		}
 
-		// Generate new name
-		newName := res.Name
-		if nameSuffixEnabled {
-			newName = GenerateName(res.Name, hookEvent)
-		}
-
 		// Update the cloned resource
-		if err := updateSplitResource(cloned, hookEvent, weights[hookEvent], newName, envEnabled); err != nil {
-			return nil, err
-		}
-
-		// Marshal the result
-		out, err := marshalNode(cloned)
+		configMap, err := p.updateSplitResource(cloned, spec, names[i], truncated[i], envEnabled)
 		if err != nil {
 			return nil, err
 		}
-		results = append(results, out)
+
+		results = append(results, withConfigMap(configMap, cloned)...)
 	}
 
 	return results, nil
@@ -54,7 +52,10 @@ func cloneNode(node *yaml.Node) (*yaml.Node, error) {
 }
 
 // updateSplitResource updates a cloned resource for a specific hook.
-func updateSplitResource(node *yaml.Node, hookEvent string, weight int, newName string, envEnabled bool) error {
+// A non-empty spec.deletePolicy replaces the inherited helm.sh/hook-delete-policy.
+// truncated records in the report that newName was shortened. It
+// returns the generated ConfigMap in envFrom mode.
+func (p *Processor) updateSplitResource(node *yaml.Node, spec hookSpec, newName string, truncated, envEnabled bool) (*yaml.Node, error) {
 	content := node
 	if node.Kind == yaml.DocumentNode && len(node.Content) > 0 {
 		content = node.Content[0]
@@ -62,30 +63,49 @@ func updateSplitResource(node *yaml.Node, hookEvent string, weight int, newName
 
 	// Update metadata name
 	if err := setMetadataName(content, newName); err != nil {
-		return err
+		return nil, err
 	}
 
 	// Set single hook event
-	if err := setAnnotation(content, annotationHook, hookEvent); err != nil {
-		return err
+	if err := setAnnotation(content, annotationHook, spec.event); err != nil {
+		return nil, err
 	}
 
 	// Set weight
-	if err := setAnnotation(content, annotationHookWeight, itoa(weight)); err != nil {
-		return err
+	if err := setAnnotation(content, annotationHookWeight, itoa(spec.weight)); err != nil {
+		return nil, err
+	}
+
+	// Set per-hook delete policy
+	if spec.deletePolicy != "" {
+		if err := setAnnotation(content, annotationHookDeletePolicy, spec.deletePolicy); err != nil {
+			return nil, err
+		}
 	}
 
-	// Remove hook-weights as it's been processed
-	removeAnnotation(content, annotationHookWeights)
+	// Remove helm-hooks' own annotations as they've been processed
+	p.removeProcessedAnnotations(content)
+
+	// Apply the per-hook patch before env injection so patched containers get env vars
+	if spec.patch != nil {
+		if err := spec.patch.apply(content); err != nil {
+			return nil, fmt.Errorf("applying %s: %w", p.eventPatchKey(spec.event), err)
+		}
+	}
 
 	// Inject environment variables if enabled
+	var containers []string
+	var configMap *yaml.Node
 	if envEnabled {
-		if err := injectEnvVars(content, hookEvent, weight); err != nil {
-			return err
+		var err error
+		if containers, configMap, err = p.injectEnv(content, spec, newName); err != nil {
+			return nil, err
 		}
 	}
 
-	return nil
+	p.recorder.addOutput(OutputReport{Name: newName, Truncated: truncated, Event: spec.event, Weight: spec.weight, EnvContainers: containers})
+
+	return configMap, nil
 }
 
 // setMetadataName updates the name in metadata.
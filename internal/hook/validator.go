@@ -1,8 +1,8 @@
 package hook
 
 import (
+	"errors"
 	"fmt"
-	"strings"
 )
 
 // Valid Helm hook events
@@ -20,55 +20,170 @@ var validHooks = map[string]bool{
 	"test-failure":        true, // deprecated but still valid
 }
 
-// validateHooks validates hook events for a resource.
-func validateHooks(hooks []string, resourceName string) error {
-	seen := make(map[string]bool)
+// hookConfig is the hook configuration of a resource, parsed from its
+// annotations.
+type hookConfig struct {
+	// hooks are the hook events, without duplicates.
+	hooks    []string
+	weights  map[string]int
+	policies map[string]string
+	names    envNames
+	envs     map[string][]envVar
+	patches  map[string]*hookPatch
+}
+
+// problemList collects the problems found in the annotations of one
+// resource, so that all of them are reported rather than the first.
+type problemList []*annotationError
+
+// add records a problem with an annotation ("" if unknown).
+func (l *problemList) add(annotation string, err error) {
+	*l = append(*l, &annotationError{annotation: annotation, err: err})
+}
+
+// addError records err under the annotation it names, if any.
+func (l *problemList) addError(err error) {
+	var annErr *annotationError
+	if errors.As(err, &annErr) {
+		l.add(annErr.annotation, annErr.err)
+	} else {
+		l.add("", err)
+	}
+}
+
+// problems converts the list into lint Problems of a document.
+func (l problemList) problems(index int, res *Resource) []Problem {
+	var problems []Problem
+	for _, e := range l {
+		problems = append(problems, Problem{
+			Document:   index,
+			Kind:       res.Kind,
+			Name:       res.Name,
+			Annotation: e.annotation,
+			Message:    e.err.Error(),
+		})
+	}
+	return problems
+}
+
+// err joins the problems into one error for the named resource, or
+// returns nil if there are none.
+func (l problemList) err(resourceName string) error {
+	errs := make([]error, len(l))
+	for i, e := range l {
+		if e.annotation == "" {
+			errs[i] = fmt.Errorf("resource %q: %w", resourceName, e.err)
+		} else {
+			errs[i] = fmt.Errorf("resource %q: %w", resourceName, e)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// parseHookConfig parses and validates the hook annotations of res:
+// events, weights, delete policies, dependencies, env vars, patches and
+// the name template. Every annotation is checked and all problems are
+// returned; the configuration is only usable when there are none.
+// Processing, Lint and ValidateAnnotations share it.
+func (p *Processor) parseHookConfig(res *Resource) (*hookConfig, problemList) {
+	var problems problemList
+	cfg := &hookConfig{policies: map[string]string{}}
+
+	weightsKey := p.cfg.annotationKey(annotationHookWeights)
+	hookValue, hasHook := res.Annotations[annotationHook]
+	weightsValue, hasWeights := res.Annotations[weightsKey]
+
+	// Determine hook events and the annotation they came from
+	var hooks []string
+	hooksAnnotation := annotationHook
+	if hasWeights && !hasHook {
+		hooksAnnotation = weightsKey
+		var err error
+		if hooks, err = extractHooksFromWeights(weightsValue); err != nil {
+			problems.add(hooksAnnotation, err)
+			return cfg, problems
+		}
+	} else {
+		hooks = parseHookEvents(hookValue)
+		if len(hooks) == 0 {
+			problems.add(hooksAnnotation, fmt.Errorf("empty hook annotation"))
+			return cfg, problems
+		}
+	}
 
+	// Report every invalid or duplicate hook, not just the first
+	seen := make(map[string]bool)
 	for _, h := range hooks {
-		// Check for valid hook name
 		if !validHooks[h] {
-			return fmt.Errorf("resource %q has invalid hook %q", resourceName, h)
+			problems.add(hooksAnnotation, fmt.Errorf("invalid hook %q", h))
+		} else if !p.cfg.hookAllowed(h) {
+			problems.add(hooksAnnotation, fmt.Errorf("hook %q is not allowed", h))
 		}
-
-		// Check for duplicates
 		if seen[h] {
-			return fmt.Errorf("resource %q has duplicate hook %q", resourceName, h)
+			problems.add(hooksAnnotation, fmt.Errorf("duplicate hook %q", h))
+			continue
 		}
 		seen[h] = true
+		cfg.hooks = append(cfg.hooks, h)
 	}
 
-	return nil
-}
+	weights, err := p.parseWeights(res.Annotations, cfg.hooks)
+	if err != nil {
+		weightAnnotation := annotationHookWeight
+		if hasWeights {
+			weightAnnotation = weightsKey
+		}
+		problems.add(weightAnnotation, err)
+	}
+	cfg.weights = weights
 
-// ValidateAnnotations performs validation on hook annotations before processing.
-func ValidateAnnotations(annotations map[string]string, resourceName string) error {
-	hookValue, hasHook := annotations[annotationHook]
-	if !hasHook {
-		return nil // Not a hook, no validation needed
+	policiesKey := p.cfg.annotationKey(annotationHookDeletePolicies)
+	if policiesValue, ok := res.Annotations[policiesKey]; ok {
+		if cfg.policies, err = parseDeletePolicies(policiesValue, cfg.hooks); err != nil {
+			problems.add(policiesKey, err)
+		}
 	}
 
-	hooks := parseHookEvents(hookValue)
-	if len(hooks) == 0 {
-		return fmt.Errorf("resource %q has empty %s annotation", resourceName, annotationHook)
+	// Dependencies are resolved across documents; here they are only
+	// validated
+	dependsOnKey := p.cfg.annotationKey(annotationHookDependsOn)
+	if dependsOnValue, ok := res.Annotations[dependsOnKey]; ok {
+		if _, err := parseDependsOn(dependsOnValue, cfg.hooks); err != nil {
+			problems.add(dependsOnKey, err)
+		}
 	}
 
-	// Validate hook names
-	if err := validateHooks(hooks, resourceName); err != nil {
-		return err
+	if cfg.names, err = p.envNames(res); err != nil {
+		problems.addError(err)
+	} else if cfg.envs, err = p.parseHookEnvVars(res, cfg.hooks, cfg.names); err != nil {
+		problems.addError(err)
+	}
+	if cfg.patches, err = p.parseHookPatches(res, cfg.hooks); err != nil {
+		problems.addError(err)
+	}
+	if _, _, err := p.nameTemplate(res); err != nil {
+		problems.addError(err)
 	}
 
-	// Validate weight format if present
-	if weightsVal, ok := annotations[annotationHookWeights]; ok {
-		if _, err := parseExplicitWeights(weightsVal, hooks); err != nil {
-			return fmt.Errorf("resource %q: %w", resourceName, err)
-		}
-	} else if weightVal, ok := annotations[annotationHookWeight]; ok {
-		weightParts := strings.Split(weightVal, ",")
-		if len(weightParts) > 1 && len(weightParts) != len(hooks) {
-			return fmt.Errorf("resource %q: hook count (%d) does not match weight count (%d)",
-				resourceName, len(hooks), len(weightParts))
-		}
+	return cfg, problems
+}
+
+// ValidateAnnotations performs validation on hook annotations before processing
+// using the default configuration.
+func ValidateAnnotations(annotations map[string]string, resourceName string) error {
+	return defaultProcessor.ValidateAnnotations(annotations, resourceName)
+}
+
+// ValidateAnnotations performs validation on hook annotations before
+// processing. It runs the same checks as processing and returns every
+// problem found, joined into one error.
+func (p *Processor) ValidateAnnotations(annotations map[string]string, resourceName string) error {
+	_, hasHook := annotations[annotationHook]
+	_, hasWeights := annotations[p.cfg.annotationKey(annotationHookWeights)]
+	if !hasHook && !hasWeights {
+		return nil // Not a hook, no validation needed
 	}
 
-	return nil
+	_, problems := p.parseHookConfig(&Resource{Name: resourceName, Annotations: annotations})
+	return problems.err(resourceName)
 }
@@ -0,0 +1,369 @@
+// Package hooks is the importable Go API of helm-hooks.
+// It exposes a configurable Processor that splits multi-hook resources,
+// applies per-hook weights, and injects environment variables, exactly
+// like the helm-hooks post-renderer.
+//
+//	p := hooks.New(hooks.WithEnvInjection(false), hooks.WithMaxNameLength(52))
+//	out, err := p.Process(rendered)
+package hooks
+
+import (
+	"context"
+	"io"
+	"strings"
+
+	"github.com/agk/helm-hooks/internal/hook"
+)
+
+// Problem describes a single issue found by Lint.
+type Problem = hook.Problem
+
+// ExecutionPlan describes the order in which Helm runs hooks for an operation.
+type ExecutionPlan = hook.ExecutionPlan
+
+// PlanPhase is one step of an ExecutionPlan.
+type PlanPhase = hook.PlanPhase
+
+// PlannedHook is a single hook resource in an ExecutionPlan.
+type PlannedHook = hook.PlannedHook
+
+// ManifestDiff describes how processing changed a manifest stream.
+type ManifestDiff = hook.ManifestDiff
+
+// DocumentDiff describes how one input document was changed.
+type DocumentDiff = hook.DocumentDiff
+
+// OutputDiff describes one document produced from an input document.
+type OutputDiff = hook.OutputDiff
+
+// FieldChange is a single difference between an input document and an output.
+type FieldChange = hook.FieldChange
+
+// ErrFunctionFailed is returned by ProcessResourceList when at least one
+// item was rejected.
+var ErrFunctionFailed = hook.ErrFunctionFailed
+
+// Warning is a non-fatal problem found while processing a resource.
+type Warning = hook.Warning
+
+// Report records what processing did to each input document.
+type Report = hook.Report
+
+// DocumentReport describes one input document in a Report.
+type DocumentReport = hook.DocumentReport
+
+// OutputReport describes one hook resource produced from an input document.
+type OutputReport = hook.OutputReport
+
+// Actions recorded in a DocumentReport.
+const (
+	ActionPassthrough = hook.ActionPassthrough
+	ActionEnhanced    = hook.ActionEnhanced
+	ActionSplit       = hook.ActionSplit
+)
+
+// Output profiles accepted by WithProfile.
+const (
+	ProfileHelm   = hook.ProfileHelm
+	ProfileArgoCD = hook.ProfileArgoCD
+)
+
+// Env modes accepted by WithEnvMode.
+const (
+	EnvModeEnv     = hook.EnvModeEnv
+	EnvModeEnvFrom = hook.EnvModeEnvFrom
+)
+
+// ConfigFile is the content of a .helm-hooks.yaml configuration file.
+type ConfigFile = hook.ConfigFile
+
+// LoadConfigFile reads and validates a configuration file.
+func LoadConfigFile(path string) (*ConfigFile, error) {
+	return hook.LoadConfigFile(path)
+}
+
+// ParseConfigFile parses and validates the content of a configuration file.
+func ParseConfigFile(data []byte) (*ConfigFile, error) {
+	return hook.ParseConfigFile(data)
+}
+
+// Release describes the Helm release being rendered, for WithRelease.
+type Release = hook.Release
+
+// ContainerPaths locates the containers of a custom resource, for
+// WithContainerPaths.
+type ContainerPaths = hook.ContainerPaths
+
+// Option configures a Processor.
+type Option func(*hook.Config)
+
+// WithConfigFile applies the settings of a configuration file. Options
+// after it override the file.
+func WithConfigFile(f *ConfigFile) Option {
+	return func(c *hook.Config) {
+		f.Apply(c)
+	}
+}
+
+// WithEnvInjection sets whether HELM_HOOK_* env vars are injected when a
+// resource has no helm.sh/hook-env annotation. Default: true.
+func WithEnvInjection(enabled bool) Option {
+	return func(c *hook.Config) {
+		c.EnvDefault = enabled
+	}
+}
+
+// WithNameSuffix sets whether split resources get a "-<event>" name suffix
+// when a resource has no helm.sh/hook-name-suffix annotation. Default: true.
+func WithNameSuffix(enabled bool) Option {
+	return func(c *hook.Config) {
+		c.NameSuffixDefault = enabled
+	}
+}
+
+// WithEnvVarNames sets the names of the injected hook event and weight
+// variables. helm.sh/hook-env-names overrides them per resource.
+// Default: HELM_HOOK_EVENT and HELM_HOOK_WEIGHT.
+func WithEnvVarNames(event, weight string) Option {
+	return func(c *hook.Config) {
+		c.EventEnvVar = event
+		c.WeightEnvVar = weight
+	}
+}
+
+// WithEnvVarPrefix sets a prefix for the names of the injected hook event
+// and weight variables, e.g. "APP_". helm.sh/hook-env-prefix overrides it
+// per resource. Default: none.
+func WithEnvVarPrefix(prefix string) Option {
+	return func(c *hook.Config) {
+		c.EnvVarPrefix = prefix
+	}
+}
+
+// WithDownwardEnv injects Downward API variables from the given sources:
+// "pod" adds POD_NAME, POD_NAMESPACE and NODE_NAME fieldRefs, "hook"
+// reads the hook event and weight from pod annotations instead of
+// literal values. helm.sh/hook-env-downward overrides it per resource.
+// Default: none.
+func WithDownwardEnv(sources ...string) Option {
+	return func(c *hook.Config) {
+		c.DownwardEnv = sources
+	}
+}
+
+// WithNameTemplate names the clones of split resources with a Go
+// template, e.g. "{{.Name}}-{{.EventShort}}" for "myapp-pi". Fields:
+// Name, Kind, Event, EventShort, Index, Weight. helm.sh/hook-name-template
+// overrides it per resource. Default: "<name>-<event>".
+func WithNameTemplate(text string) Option {
+	return func(c *hook.Config) {
+		c.NameTemplate = text
+	}
+}
+
+// WithEnvMode selects how variables reach containers: EnvModeEnv edits
+// each container's env, EnvModeEnvFrom generates a ConfigMap per hook,
+// one weight lower, that containers reference with envFrom.
+// helm.sh/hook-env-mode overrides it per resource. Default: EnvModeEnv.
+func WithEnvMode(mode string) Option {
+	return func(c *hook.Config) {
+		c.EnvMode = mode
+	}
+}
+
+// WithEphemeralContainers also injects env vars into ephemeralContainers.
+// Default: false.
+func WithEphemeralContainers(enabled bool) Option {
+	return func(c *hook.Config) {
+		c.EnvEphemeralContainers = enabled
+	}
+}
+
+// WithContainerPaths tells env injection where the containers of custom
+// resources are, e.g. {Kind: "BatchRun", Paths: []string{"spec.runner.containers[]"}}.
+// Entries take precedence over the built-in ones for Argo Workflows,
+// Tekton, KEDA ScaledJob and Strimzi. helm.sh/hook-env-paths overrides
+// them per resource.
+func WithContainerPaths(entries ...ContainerPaths) Option {
+	return func(c *hook.Config) {
+		c.ContainerPaths = append(c.ContainerPaths, entries...)
+	}
+}
+
+// WithRelease injects the release context into hook containers:
+// HELM_RELEASE_NAME, HELM_RELEASE_NAMESPACE, HELM_RELEASE_REVISION,
+// HELM_CHART_NAME and HELM_CHART_VERSION, for each non-empty field.
+// Default: none.
+func WithRelease(release Release) Option {
+	return func(c *hook.Config) {
+		c.Release = release
+	}
+}
+
+// WithIncludeKinds restricts processing to hooks of the given kinds.
+// Hooks of other kinds are left unchanged. Default: all kinds.
+func WithIncludeKinds(kinds ...string) Option {
+	return func(c *hook.Config) {
+		c.IncludeKinds = make(map[string]bool, len(kinds))
+		for _, k := range kinds {
+			c.IncludeKinds[k] = true
+		}
+	}
+}
+
+// WithExcludeKinds leaves hooks of the given kinds unchanged.
+func WithExcludeKinds(kinds ...string) Option {
+	return func(c *hook.Config) {
+		c.ExcludeKinds = make(map[string]bool, len(kinds))
+		for _, k := range kinds {
+			c.ExcludeKinds[k] = true
+		}
+	}
+}
+
+// WithAllowedHooks restricts the accepted hook events. Resources using any
+// other event are rejected. Default: all Helm hook events.
+func WithAllowedHooks(events ...string) Option {
+	return func(c *hook.Config) {
+		c.AllowedHooks = make(map[string]bool, len(events))
+		for _, e := range events {
+			c.AllowedHooks[e] = true
+		}
+	}
+}
+
+// WithMaxNameLength sets the name length limit for split resources.
+// Longer names are truncated with a deterministic hash. Limits below 24,
+// too short for a truncated name, make processing fail. Default: 63.
+func WithMaxNameLength(n int) Option {
+	return func(c *hook.Config) {
+		c.MaxNameLength = n
+	}
+}
+
+// WithAnnotationPrefix sets the prefix of helm-hooks' own annotations
+// (hook-weights, hook-env, hook-name-suffix, hook-delete-policies).
+// Native Helm annotations such as helm.sh/hook keep their helm.sh/ prefix.
+// Default: "helm.sh/".
+func WithAnnotationPrefix(prefix string) Option {
+	return func(c *hook.Config) {
+		if prefix != "" && !strings.HasSuffix(prefix, "/") {
+			prefix += "/"
+		}
+		c.AnnotationPrefix = prefix
+	}
+}
+
+// WithFlattenLists sets whether the items of List documents (v1/List or any
+// *List kind) are emitted as separate documents. When false, hook items are
+// processed and split within the list. Default: false.
+func WithFlattenLists(enabled bool) Option {
+	return func(c *hook.Config) {
+		c.FlattenLists = enabled
+	}
+}
+
+// WithProfile selects the output format of hook annotations. ProfileArgoCD
+// translates hooks, weights and delete policies into Argo CD hooks and
+// sync waves. Default: ProfileHelm.
+func WithProfile(profile string) Option {
+	return func(c *hook.Config) {
+		c.Profile = profile
+	}
+}
+
+// WithStrictOrdering rejects hooks of the same event that share a weight,
+// so the execution order never depends on resource names. Default: false.
+func WithStrictOrdering(enabled bool) Option {
+	return func(c *hook.Config) {
+		c.StrictOrdering = enabled
+	}
+}
+
+// WithWarningHandler sets a function that receives non-fatal problems,
+// such as hook events without an Argo CD equivalent.
+func WithWarningHandler(fn func(Warning)) Option {
+	return func(c *hook.Config) {
+		c.OnWarning = fn
+	}
+}
+
+// Processor enhances hook resources in rendered Helm manifests.
+type Processor struct {
+	p *hook.Processor
+}
+
+// New returns a Processor with the default configuration modified by opts.
+func New(opts ...Option) *Processor {
+	cfg := hook.DefaultConfig()
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	return &Processor{p: hook.NewProcessor(cfg)}
+}
+
+// Process takes multi-document YAML and returns the enhanced YAML.
+func (p *Processor) Process(input []byte) ([]byte, error) {
+	return p.p.Process(input)
+}
+
+// ProcessStream reads multi-document YAML from r and writes the enhanced
+// YAML to w one document at a time, with memory bounded by the largest
+// document. It stops when ctx is cancelled.
+func (p *Processor) ProcessStream(ctx context.Context, r io.Reader, w io.Writer) error {
+	return p.p.ProcessStream(ctx, r, w)
+}
+
+// ProcessWithReport is Process that also returns a Report of what was done
+// to each document: passed through, enhanced or split, with the resulting
+// names, events, weights and env-injected containers.
+func (p *Processor) ProcessWithReport(input []byte) ([]byte, *Report, error) {
+	return p.p.ProcessWithReport(input)
+}
+
+// ProcessStreamWithReport is ProcessStream that also returns a Report.
+func (p *Processor) ProcessStreamWithReport(ctx context.Context, r io.Reader, w io.Writer) (*Report, error) {
+	return p.p.ProcessStreamWithReport(ctx, r, w)
+}
+
+// ProcessResourceList runs the processor as a KRM function: it reads a
+// ResourceList, transforms each item and returns the resulting ResourceList.
+// Problems are reported in its results field; if any item was rejected,
+// ErrFunctionFailed is returned together with the output.
+func (p *Processor) ProcessResourceList(input []byte) ([]byte, error) {
+	return p.p.ProcessResourceList(input)
+}
+
+// ValidateAnnotations validates the hook annotations of a single resource
+// with the checks Process runs. All problems are returned, joined into
+// one error.
+func (p *Processor) ValidateAnnotations(annotations map[string]string, resourceName string) error {
+	return p.p.ValidateAnnotations(annotations, resourceName)
+}
+
+// Lint validates every document in input and returns all problems found.
+func (p *Processor) Lint(input []byte) []Problem {
+	return p.p.Lint(input)
+}
+
+// Plan returns the order in which Helm will run hooks for an operation
+// (install, upgrade, rollback, delete or test).
+func (p *Processor) Plan(input []byte, operation string) (*ExecutionPlan, error) {
+	return p.p.Plan(input, operation)
+}
+
+// Diff returns, for each document processing changes, the fields changed
+// in each of its outputs. Use WriteText or WriteUnified to print it.
+func (p *Processor) Diff(input []byte) (*ManifestDiff, error) {
+	return p.p.Diff(input)
+}
+
+// Process enhances input using the default configuration.
+func Process(input []byte) ([]byte, error) {
+	return hook.Process(input)
+}
+
+// ProcessStream enhances YAML from r into w using the default configuration.
+func ProcessStream(ctx context.Context, r io.Reader, w io.Writer) error {
+	return hook.ProcessStream(ctx, r, w)
+}
@@ -0,0 +1,160 @@
+package hooks
+
+import (
+	"strings"
+	"testing"
+)
+
+const multiHookJob = `apiVersion: batch/v1
+kind: Job
+metadata:
+  name: myapp-migration
+  annotations:
+    helm.sh/hook: pre-install,post-upgrade
+    example.com/hook-weights: "-100,200"
+spec:
+  template:
+    spec:
+      containers:
+        - name: migrate
+          image: busybox
+`
+
+func TestProcess_Default(t *testing.T) {
+	output, err := Process([]byte(multiHookJob))
+	if err != nil {
+		t.Fatalf("Process failed: %v", err)
+	}
+
+	result := string(output)
+	if !strings.Contains(result, "myapp-migration-pre-install") {
+		t.Error("Expected pre-install suffixed name")
+	}
+	if !strings.Contains(result, "HELM_HOOK_EVENT") {
+		t.Error("Expected env injection by default")
+	}
+}
+
+func TestProcessor_Options(t *testing.T) {
+	p := New(
+		WithEnvInjection(false),
+		WithNameSuffix(false),
+		WithAnnotationPrefix("example.com"),
+	)
+
+	output, err := p.Process([]byte(multiHookJob))
+	if err != nil {
+		t.Fatalf("Process failed: %v", err)
+	}
+
+	result := string(output)
+	if strings.Contains(result, "HELM_HOOK_EVENT") {
+		t.Error("Env injection should be disabled")
+	}
+	if strings.Contains(result, "myapp-migration-pre-install") {
+		t.Error("Name suffix should be disabled")
+	}
+	if !strings.Contains(result, `helm.sh/hook-weight: "-100"`) || !strings.Contains(result, `helm.sh/hook-weight: "200"`) {
+		t.Errorf("Expected weights from prefixed annotation, got:\n%s", result)
+	}
+	if strings.Contains(result, "example.com/hook-weights") {
+		t.Error("Prefixed hook-weights should be removed after processing")
+	}
+}
+
+func TestProcessor_AllowedHooks(t *testing.T) {
+	p := New(WithAllowedHooks("pre-install", "pre-upgrade"))
+
+	_, err := p.Process([]byte(strings.Replace(multiHookJob, "example.com/", "helm.sh/", 1)))
+	if err == nil || !strings.Contains(err.Error(), "not allowed") {
+		t.Errorf("Expected post-upgrade to be rejected, got: %v", err)
+	}
+}
+
+func TestProcessor_MaxNameLength(t *testing.T) {
+	p := New(WithMaxNameLength(24))
+
+	output, err := p.Process([]byte(multiHookJob))
+	if err != nil {
+		t.Fatalf("Process failed: %v", err)
+	}
+
+	for _, line := range strings.Split(string(output), "\n") {
+		line = strings.TrimSpace(line)
+		if strings.HasPrefix(line, "name: myapp") {
+			name := strings.TrimPrefix(line, "name: ")
+			if len(name) > 24 {
+				t.Errorf("Name %q exceeds 24 chars", name)
+			}
+		}
+	}
+
+	// Shorter limits cannot fit a truncated name
+	if _, err := New(WithMaxNameLength(20)).Process([]byte(multiHookJob)); err == nil {
+		t.Error("Expected maxNameLength 20 to be rejected")
+	}
+}
+
+func TestProcessor_ProcessWithReport(t *testing.T) {
+	_, report, err := New().ProcessWithReport([]byte(multiHookJob))
+	if err != nil {
+		t.Fatalf("ProcessWithReport failed: %v", err)
+	}
+
+	if len(report.Documents) != 1 || report.Documents[0].Action != ActionSplit {
+		t.Fatalf("Expected one split document, got %+v", report.Documents)
+	}
+	if outputs := report.Documents[0].Outputs; len(outputs) != 2 || outputs[0].Name != "myapp-migration-pre-install" {
+		t.Errorf("Unexpected outputs: %+v", outputs)
+	}
+}
+
+func TestProcessor_Diff(t *testing.T) {
+	diff, err := New().Diff([]byte(multiHookJob))
+	if err != nil {
+		t.Fatalf("Diff failed: %v", err)
+	}
+
+	if len(diff.Documents) != 1 || len(diff.Documents[0].Outputs) != 2 {
+		t.Fatalf("Expected one document split in two, got %+v", diff.Documents)
+	}
+	if changes := diff.Documents[0].Outputs[0].Changes; len(changes) == 0 || changes[0].Path != "metadata.name" {
+		t.Errorf("Expected a metadata.name change first, got %+v", changes)
+	}
+}
+
+func TestProcessor_DownwardEnv(t *testing.T) {
+	output, err := New(WithDownwardEnv("pod", "hook")).Process([]byte(multiHookJob))
+	if err != nil {
+		t.Fatalf("Process failed: %v", err)
+	}
+
+	result := string(output)
+	if !strings.Contains(result, "fieldPath: metadata.annotations['helm.sh/hook']") || !strings.Contains(result, "fieldPath: spec.nodeName") {
+		t.Errorf("Expected fieldRef env vars, got:\n%s", result)
+	}
+}
+
+func TestProcessor_ConfigFile(t *testing.T) {
+	f, err := ParseConfigFile([]byte(`envVars:
+  event: HOOK_EVENT
+  weight: HOOK_WEIGHT
+annotationPrefix: example.com
+`))
+	if err != nil {
+		t.Fatalf("ParseConfigFile failed: %v", err)
+	}
+
+	output, err := New(WithConfigFile(f), WithNameSuffix(false)).Process([]byte(multiHookJob))
+	if err != nil {
+		t.Fatalf("Process failed: %v", err)
+	}
+
+	result := string(output)
+	if !strings.Contains(result, "name: HOOK_EVENT") || strings.Contains(result, "HELM_HOOK_EVENT") {
+		t.Errorf("Expected renamed env vars, got:\n%s", result)
+	}
+	if strings.Contains(result, "myapp-migration-pre-install") {
+		t.Error("Option after WithConfigFile should override the default")
+	}
+}